@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// Фаза остановки компонента: фазы останавливаются по порядку, сначала перестаём принимать запросы
+// и дожидаемся активных, затем фоновые задачи, и только потом закрываем хранилища, которыми они пользуются
+type Phase int
+
+const (
+	PhaseHTTP    Phase = iota // HTTP сервер (drain активных запросов)
+	PhaseWorkers              // фоновые задачи
+	PhaseStorage              // соединения с Redis и Postgres
+)
+
+// Компонент приложения с хуками запуска и остановки (любой из хуков может быть nil)
+type Hook struct {
+	Name    string
+	Phase   Phase
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Запуск и остановка компонентов приложения. Компоненты запускаются в порядке регистрации,
+// останавливаются по фазам, внутри фазы - в обратном порядке регистрации
+type Lifecycle struct {
+	hooks   []Hook
+	started int // сколько первых хуков запущено (им нужна остановка)
+	log     *slog.Logger
+}
+
+func NewLifecycle(log *slog.Logger) *Lifecycle {
+	return &Lifecycle{log: log}
+}
+
+// Регистрация компонента
+func (l *Lifecycle) Append(hook Hook) {
+	l.hooks = append(l.hooks, hook)
+}
+
+// Запуск компонентов, ещё не запущенных. При ошибке уже запущенные компоненты останавливаются
+func (l *Lifecycle) Start(ctx context.Context) error {
+	for l.started < len(l.hooks) {
+		hook := l.hooks[l.started]
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				err = fmt.Errorf("start %s: %w", hook.Name, err)
+				return errors.Join(err, l.Stop(ctx))
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Остановка запущенных компонентов по фазам. Ошибка одного компонента не прерывает остановку остальных,
+// возвращаются все ошибки
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	// обратный порядок регистрации, устойчивая сортировка по фазе его сохраняет
+	hooks := make([]Hook, 0, l.started)
+	for i := l.started - 1; i >= 0; i-- {
+		hooks = append(hooks, l.hooks[i])
+	}
+	sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].Phase < hooks[j].Phase })
+	l.started = 0
+
+	var errs []error
+	for _, hook := range hooks {
+		if hook.OnStop == nil {
+			continue
+		}
+		l.log.Info("stopping component", "component", hook.Name)
+		if err := hook.OnStop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", hook.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"simple_gin_server/pkg/logger"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// хук, записывающий запуск и остановку в общий журнал
+func recordingHook(name string, phase Phase, journal *[]string, stopErr error) Hook {
+	return Hook{
+		Name:  name,
+		Phase: phase,
+		OnStart: func(context.Context) error {
+			*journal = append(*journal, "start "+name)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			*journal = append(*journal, "stop "+name)
+			return stopErr
+		},
+	}
+}
+
+// тест порядка: запуск в порядке регистрации, остановка по фазам (HTTP, фоновые задачи, хранилища),
+// ошибки остановки собираются, остановка не прерывается
+func TestLifecycle_StopOrder(t *testing.T) {
+	var journal []string
+	lc := NewLifecycle(logger.Nop())
+	lc.Append(recordingHook("postgres", PhaseStorage, &journal, errors.New("pool busy")))
+	lc.Append(recordingHook("redis", PhaseStorage, &journal, nil))
+	lc.Append(recordingHook("cleanup", PhaseWorkers, &journal, nil))
+	lc.Append(recordingHook("http", PhaseHTTP, &journal, errors.New("deadline exceeded")))
+
+	assert.NoError(t, lc.Start(context.Background()))
+	err := lc.Stop(context.Background())
+
+	assert.Equal(t, []string{
+		"start postgres", "start redis", "start cleanup", "start http",
+		"stop http", "stop cleanup", "stop redis", "stop postgres",
+	}, journal)
+	assert.ErrorContains(t, err, "stop http: deadline exceeded")
+	assert.ErrorContains(t, err, "stop postgres: pool busy")
+
+	// повторная остановка ничего не делает
+	journal = nil
+	assert.NoError(t, lc.Stop(context.Background()))
+	assert.Empty(t, journal)
+}
+
+// тест ошибки запуска: останавливаются только уже запущенные компоненты
+func TestLifecycle_StartFailure(t *testing.T) {
+	var journal []string
+	lc := NewLifecycle(logger.Nop())
+	lc.Append(recordingHook("postgres", PhaseStorage, &journal, nil))
+	lc.Append(Hook{Name: "http", Phase: PhaseHTTP, OnStart: func(context.Context) error {
+		return errors.New("address already in use")
+	}})
+	lc.Append(recordingHook("cleanup", PhaseWorkers, &journal, nil))
+
+	err := lc.Start(context.Background())
+
+	assert.ErrorContains(t, err, "start http: address already in use")
+	assert.Equal(t, []string{"start postgres", "stop postgres"}, journal)
+}
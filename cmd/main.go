@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"simple_gin_server/configs"
 	"simple_gin_server/internal/users"
+	"simple_gin_server/pkg/logger"
 	"syscall"
-	"time"
 )
 
 func main() {
@@ -15,12 +18,42 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Инициализируем сервер
-	server := NewServer(ctx)
+	// Конфиг: значения по умолчанию, затем YAML файл, затем переменные окружения
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML или TOML файл конфигурации")
+	envPrefix := flag.String("env-prefix", os.Getenv("CONFIG_ENV_PREFIX"), "префикс переменных окружения (WISP_ - WISP_DSN вместо DSN)")
+	flag.Parse()
+
+	conf, err := configs.Load(configs.LoadOptions{File: *configFile, EnvPrefix: *envPrefix})
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+
+	// Логгер по конфигу, он же логгер по умолчанию для пакетов без внедрённого логгера (и стандартного log)
+	logg, err := logger.New(conf.Log, os.Stdout)
+	if err != nil {
+		log.Fatalf("Logger error: %v", err)
+	}
+	slog.SetDefault(logg)
+
+	// Подкоманда управления миграциями: migrate up|down|status (нужен только DSN)
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrateCommand(ctx, conf, args[1:], logg); err != nil {
+			fatal(logg, "migration failed", err)
+		}
+		return
+	}
+
+	if err := conf.Validate(); err != nil {
+		fatal(logg, "invalid config", err)
+	}
+
+	// Инициализируем сервер (внутри применяются миграции), компоненты регистрируются в lifecycle
+	lifecycle := NewLifecycle(logg)
+	server := NewServer(ctx, conf, lifecycle, logg)
 
 	// Проверяем и создаем администратора перед запуском сервера
 	if err := ensureAdminExists(ctx, server); err != nil {
-		log.Printf("Admin initialization error: %v", err)
+		logg.Error("admin initialization failed", "error", err)
 		// Не прерываем работу, но логируем ошибку
 	}
 
@@ -28,42 +61,44 @@ func main() {
 	exit := make(chan os.Signal, 1)
 	signal.Notify(exit, syscall.SIGINT, syscall.SIGTERM)
 
-	//канал для ошибок запуска сервера
-	serverErr := make(chan error, 1)
-
-	// Запуск сервера в отдельной горутине
-	go func() {
-		if err := server.Run(); err != nil {
-			serverErr <- err
-		}
-	}()
+	// Запуск компонентов (HTTP сервер обрабатывает запросы в отдельной горутине)
+	if err := lifecycle.Start(ctx); err != nil {
+		fatal(logg, "startup failed", err)
+	}
 
 	// Ожидаем сигнал завершения или ошибку сервера
 	select {
 	case <-exit:
-		log.Println("Shutting down server...")
-	case err := <-serverErr:
-		log.Printf("Server error: %v", err)
+		logg.Info("shutting down server")
+	case err := <-server.Errors():
+		logg.Error("server error, shutting down", "error", err)
 	}
 
-	// Завершаем работу с таймаутом
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	// Завершаем работу с таймаутом: сначала HTTP (активные запросы успевают завершиться),
+	// затем фоновые задачи, затем Redis и Postgres
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), conf.HTTP.ShutdownTimeout)
 	defer shutdownCancel()
 
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("Server shutdown error: %v", err)
+	if err := lifecycle.Stop(shutdownCtx); err != nil {
+		fatal(logg, "server shutdown failed", err)
 	}
 
-	log.Println("Server exited properly")
+	logg.Info("server exited properly")
+}
+
+// запись ошибки в лог и завершение процесса
+func fatal(log *slog.Logger, msg string, err error) {
+	log.Error(msg, "error", err)
+	os.Exit(1)
 }
 
 func ensureAdminExists(ctx context.Context, server *Server) error {
 	// Проверяем обязательные переменные окружения
 	if os.Getenv("ADMIN_EMAIL") == "" || os.Getenv("ADMIN_PASSWORD") == "" {
-		log.Println("Warning: ADMIN_EMAIL or ADMIN_PASSWORD not set, admin user won't be created")
+		server.log.Warn("ADMIN_EMAIL or ADMIN_PASSWORD not set, admin user won't be created")
 		return nil
 	}
 
-	userRepo := users.NewUserRepository(server.db)
+	userRepo := users.NewUserRepository(server.db, server.log)
 	return userRepo.EnsureAdminExists(ctx)
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"simple_gin_server/configs"
+	"simple_gin_server/migrations"
+	"simple_gin_server/pkg/db"
+)
+
+const migrateUsage = "usage: migrate up|down|status"
+
+// Применение встроенных в бинарник миграций к базе
+func migrateUp(ctx context.Context, pg db.PgRepoInterface, log *slog.Logger) error {
+	migrator, err := db.NewMigrator(pg, migrations.FS, log)
+	if err != nil {
+		return err
+	}
+	return migrator.Up(ctx)
+}
+
+// Подкоманда "migrate up|down|status", работает только с БД, без запуска HTTP сервера
+func runMigrateCommand(ctx context.Context, conf *configs.Config, args []string, log *slog.Logger) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	if conf.Db.Dsn == "" {
+		return errors.New("DSN is required")
+	}
+
+	pg, err := db.NewPgRepo(ctx, conf)
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+
+	migrator, err := db.NewMigrator(pg, migrations.FS, log)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-30s %s\n", "Applied At", "Migration")
+		for _, st := range statuses {
+			appliedAt := "Pending"
+			if st.AppliedAt != nil {
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%-30s %d_%s\n", appliedAt, st.Version, st.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, %s", args[0], migrateUsage)
+	}
+}
@@ -1,70 +1,106 @@
 package main
 
 import (
+	"log/slog"
 	"simple_gin_server/configs"
 	"simple_gin_server/internal/auth"
 	"simple_gin_server/internal/match"
 	"simple_gin_server/internal/profile"
+	"simple_gin_server/pkg/health"
+	"simple_gin_server/pkg/jwt_stuff"
 	"simple_gin_server/pkg/middleware"
+	"simple_gin_server/pkg/ratelimit"
+	"simple_gin_server/pkg/rbac"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Routes struct {
-	Auth    *auth.AuthHandler
-	Match   *match.MatchHandler
-	Profile *profile.ProfileHandler
-	Config  *configs.Config
+	Auth        *auth.AuthHandler
+	Match       *match.MatchHandler
+	Profile     *profile.ProfileHandler
+	Config      *configs.Config
+	Permissions *rbac.Registry
+	Tokens      middleware.TokenChecker
+	JWT         *jwt_stuff.JWT
+	Limiter     ratelimit.Limiter
+	Health      *health.Health
+	Log         *slog.Logger
 }
 
 func (r *Routes) Setup(router *gin.Engine) {
+	// Probes: без авторизации и ограничения частоты (Kubernetes, балансировщик)
+	router.GET("/livez", r.Health.LivenessHandler)   // процесс жив
+	router.GET("/readyz", r.Health.ReadinessHandler) // Postgres и Redis доступны, экземпляр не останавливается
+
+	// публичные ключи проверки access токенов для других сервисов: без ограничения частоты, их запрашивают все сервисы-потребители
+	router.GET("/.well-known/jwks.json", r.Auth.JWKSHandler)
+
+	// получение нового access токена при истечении его времени жизни, если refresh токен валиден и не в черном списке.
+	// Клиенты обновляют токены постоянно, поэтому у эндпоинта свой лимит по IP, а не общий с логином
+	router.POST("/auth/refresh", r.limit("refresh", r.Config.RateLimit.Refresh), r.Auth.ProcessRefreshTokenHandler)
+
 	// Public routes
 	public := router.Group("/")
+	public.Use(r.limit("public", r.Config.RateLimit.Public)) // лимит по IP клиента
 	{
-		public.POST("/register", middleware.ValidateAuthMiddleware(&auth.RegisterRequest{}), r.Auth.RegisterHandler) // эндпоинт для регистрации нового пользователя
-		public.POST("/login", middleware.ValidateAuthMiddleware(&auth.LoginRequest{}), r.Auth.LoginHandler)          // эндпоинт для логина зарегестрированного пользователя (в ответе выдаётся access и refresh токены)
-		public.POST("auth/refresh", r.Auth.ProcessRefreshTokenHandler)                                               // получение нового access токена при истечении его времени жизни, если refresh токен валиден и не в черном списке
+		public.POST("/register", middleware.ValidateAuthMiddleware(&auth.RegisterRequest{}), r.Auth.RegisterHandler)                    // эндпоинт для регистрации нового пользователя
+		public.POST("/login", middleware.ValidateAuthMiddleware(&auth.LoginRequest{}), r.Auth.LoginHandler)                             // эндпоинт для логина зарегестрированного пользователя (в ответе выдаётся access и refresh токены)
+		public.GET("/verify", r.Auth.VerifyEmailHandler)                                                                                // подтверждение email по ссылке из письма, после него возможен логин
+		public.POST("/password/forgot", middleware.ValidateAuthMiddleware(&auth.ForgotPasswordRequest{}), r.Auth.ForgotPasswordHandler) // запрос письма со ссылкой сброса пароля (ответ не зависит от наличия email в базе)
+		public.POST("/password/reset", middleware.ValidateAuthMiddleware(&auth.ResetPasswordRequest{}), r.Auth.ResetPasswordHandler)    // установка нового пароля по одноразовому токену из письма, все сессии закрываются
 	}
 
 	// Authenticated routes
 	authGroup := router.Group("/")
-	authGroup.Use(middleware.AuthMiddleware(r.Config))
+	authGroup.Use(middleware.AuthMiddleware(r.JWT.AccessVerifier(), r.Tokens, r.Log), r.limit("default", r.Config.RateLimit.Default)) // лимит по id пользователя
 	{
-		authGroup.GET("/health", r.Auth.Check)          // health check, ручка-проверка, что все работатет
-		authGroup.GET("/list", r.Auth.ListHandler)      // выводит список всех Email зарегестрированных юзеров
-		authGroup.POST("/logout", r.Auth.LogoutHandler) // эндпоинт для logout (помощение refresh токена в черный список redis, удалени из БД)
+		authGroup.GET("/health", r.Auth.Check)                         // health check, ручка-проверка, что все работатет
+		authGroup.GET("/list", r.Auth.ListHandler)                     // выводит список всех Email зарегестрированных юзеров
+		authGroup.POST("/logout", r.Auth.LogoutHandler)                // эндпоинт для logout (удаление сессии, которой принадлежит refresh токен)
+		authGroup.POST("/logout/all", r.Auth.LogoutAllHandler)         // logout со всех устройств (отзыв всех access и refresh токенов пользователя)
+		authGroup.GET("/sessions", r.Auth.ListSessionsHandler)         // список действующих сессий (устройств) текущего пользователя
+		authGroup.DELETE("/sessions/:id", r.Auth.DeleteSessionHandler) // отзыв своей сессии по ID (выход на другом устройстве)
+
+		// смена пароля (нужен текущий пароль, неверный учитывается блокировкой логина): все токены отзываются, текущей сессии выдаются новые
+		authGroup.POST("/password/change", middleware.ValidateAuthMiddleware(&auth.ChangePasswordRequest{}), r.Auth.ChangePasswordHandler)
 
 		// User routes
-		userGroup := authGroup.Group("/")
-		userGroup.Use(middleware.RoleCheckMiddleware("user"))
-		{
-			r.setupUserRoutes(userGroup)
-		}
-
-		// Admin routes
-		adminGroup := authGroup.Group("/")
-		adminGroup.Use(middleware.RoleCheckMiddleware("admin"))
-		{
-			r.setupAdminRoutes(adminGroup)
-		}
+		r.setupUserRoutes(authGroup)
+
+		// Admin/moderator routes
+		r.setupAdminRoutes(authGroup)
 	}
 
 }
 
+// middleware ограничения частоты запросов группы маршрутов name
+func (r *Routes) limit(name string, rule configs.RateLimitRule) gin.HandlerFunc {
+	return middleware.RateLimitMiddleware(r.Limiter, name, ratelimit.LimitFromConfig(rule), r.Log)
+}
+
+// middleware проверки права доступа (с учётом наследования ролей)
+func (r *Routes) can(permission string) gin.HandlerFunc {
+	return middleware.RequirePermission(r.Permissions, permission)
+}
+
 func (r *Routes) setupUserRoutes(group *gin.RouterGroup) {
-	group.POST("/get_new_access", r.Auth.ProcessRefreshTokenHandler)   // получение нового access токена при предоставлении валидного refresh токена в body
-	group.POST("/profiles", r.Profile.CreateNewProfileHandler)         // создание нового профиля после авторизации (входящие данные JSON)
-	group.GET("/profiles/me", r.Profile.GetMyProfileHandler)           // получение своего профиля(ответ в виде JSON)
-	group.PATCH("/profiles/me", r.Profile.UpdateMyProfileHandler)      // обновление своего профиля
-	group.DELETE("/profiles/me", r.Profile.DeleteMyProfileHandler)     // удаление своего профиля
-	group.POST("/matches/search", r.Match.SearchMatchesHandler)        // получение списка совпадений по заданным критериям (входные данные JSON)
-	group.POST("/matches/{id}/actions", r.Match.RegisterActionHandler) // регистрация действия пользователя (лайк/скип/жалоба)
-	group.GET("/matches", r.Match.GetAcceptedMatchesHandler)           // получаем список совпадений, где 2-я сторона приняла запрос
-	group.DELETE("/matches/{id}", r.Match.DeleteMetchByIdHandler)      // удалить совпадение по ID
+	group.POST("/get_new_access", middleware.RoleCheckMiddleware(r.Permissions, "user"), r.Auth.ProcessRefreshTokenHandler) // получение нового access токена при предоставлении валидного refresh токена в body
+	group.POST("/profiles", r.can(rbac.PermProfilesWrite), r.Profile.CreateNewProfileHandler)                               // создание нового профиля после авторизации (входящие данные JSON)
+	group.GET("/profiles/me", r.can(rbac.PermProfilesRead), r.Profile.GetMyProfileHandler)                                  // получение своего профиля(ответ в виде JSON)
+	group.PATCH("/profiles/me", r.can(rbac.PermProfilesWrite), r.Profile.UpdateMyProfileHandler)                            // обновление своего профиля
+	group.DELETE("/profiles/me", r.can(rbac.PermProfilesWrite), r.Profile.DeleteMyProfileHandler)                           // удаление своего профиля
+	group.POST("/profiles/:id/actions", r.can(rbac.PermMatchesWrite), r.Match.RegisterActionHandler)                        // регистрация действия пользователя (лайк/скип/жалоба) над профилем кандидата с id из поиска
+	group.GET("/matches", r.can(rbac.PermMatchesRead), r.Match.GetAcceptedMatchesHandler)                                   // получаем список совпадений, где 2-я сторона приняла запрос
+	group.DELETE("/matches/:id", r.can(rbac.PermMatchesWrite), r.Match.DeleteMetchByIdHandler)                              // удалить совпадение по ID
+
+	// получение списка совпадений по заданным критериям (входные данные JSON), поиск тяжёлый - отдельный лимит поверх общего
+	group.POST("/matches/search", r.can(rbac.PermMatchesRead), r.limit("search", r.Config.RateLimit.Search), r.Match.SearchMatchesHandler)
 
 }
 
 func (r *Routes) setupAdminRoutes(group *gin.RouterGroup) {
-	group.GET("/users", r.Auth.ListHandler)               // получить список  Email всех юзеров в базе
-	group.DELETE("/users/{id}", r.Auth.DeleteUserHandler) // удалить конкретного юзера по id
+	group.GET("/users", r.can(rbac.PermUsersRead), r.Auth.ListHandler)                    // получить список  Email всех юзеров в базе (модератор и админ)
+	group.DELETE("/users/:id", r.can(rbac.PermUsersDelete), r.Auth.DeleteUserHandler)     // удалить конкретного юзера по id (только админ)
+	group.POST("/users/:id/unlock", r.can(rbac.PermUsersWrite), r.Auth.UnlockUserHandler) // снять блокировку логина юзера после неудачных попыток (только админ)
 }
@@ -2,15 +2,26 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
+	"time"
 
 	"simple_gin_server/configs"
 	"simple_gin_server/internal/auth"
 	"simple_gin_server/internal/match"
 	"simple_gin_server/internal/profile"
+	"simple_gin_server/internal/sessions"
 	"simple_gin_server/internal/users"
 	"simple_gin_server/pkg/db"
+	"simple_gin_server/pkg/health"
+	"simple_gin_server/pkg/jwt_stuff"
+	"simple_gin_server/pkg/mailer"
+	"simple_gin_server/pkg/middleware"
+	"simple_gin_server/pkg/ratelimit"
+	"simple_gin_server/pkg/rbac"
+	"simple_gin_server/pkg/tlscert"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,90 +32,207 @@ type Server struct {
 	routes     *Routes
 	config     *configs.Config
 	db         db.PgRepoInterface
+	log        *slog.Logger
+	errs       chan error // ошибки работы HTTP сервера после запуска
 }
 
-// Конструктор для сервера
-func NewServer(ctx context.Context) *Server {
-	conf := configs.LoadConfig()
-
+// Конструктор для сервера, созданные соединения и сам сервер регистрируются в lifecycle
+func NewServer(ctx context.Context, conf *configs.Config, lifecycle *Lifecycle, log *slog.Logger) *Server {
 	// создаём экземпляр пула соединений на базе конфига и контекста
 	db_pg, err := db.NewPgRepo(ctx, conf)
 	if err != nil {
-		log.Fatal(err)
+		fatal(log, "database connection failed", err)
+	}
+	lifecycle.Append(Hook{
+		Name:  "postgres",
+		Phase: PhaseStorage,
+		OnStop: func(context.Context) error {
+			db_pg.Close()
+			return nil
+		},
+	})
+
+	// Применяем миграции до инициализации слоёв (роли и права читаются из БД)
+	if err := migrateUp(ctx, db_pg, log); err != nil {
+		fatal(log, "migration failed", err)
+	}
+
+	// загружаем роли и права доступа
+	permissions, err := rbac.LoadRegistry(ctx, db_pg)
+	if err != nil {
+		fatal(log, "failed to load permissions", err)
 	}
 
 	// создаём экземпляр reddis, используя config
-	redisRepo := db.NewRedisRepo(ctx, conf)
+	redisRepo := db.NewRedisRepo(ctx, conf, log)
+	lifecycle.Append(Hook{
+		Name:  "redis",
+		Phase: PhaseStorage,
+		OnStop: func(context.Context) error {
+			return redisRepo.Close()
+		},
+	})
+
+	// liveness и readiness пробы, readiness проверяет Postgres и Redis
+	probes := health.New(conf.HTTP.ReadinessTimeout, log)
+	probes.Add("postgres", db_pg.Ping)
+	probes.Add("redis", redisRepo.Ping)
 
 	// Инициализация слоёв приложения
 
+	// ключи подписи и проверки токенов
+	tokens, err := jwt_stuff.NewJWTFromConfig(conf.Auth)
+	if err != nil {
+		fatal(log, "failed to load JWT keys", err)
+	}
+
+	// отправка писем пользователям (подтверждение email)
+	mail, err := mailer.NewMailer(conf.Mail, log)
+	if err != nil {
+		fatal(log, "failed to create mailer", err)
+	}
+
+	// ограничение частоты запросов (Redis - общий лимит для всех экземпляров сервиса)
+	limiter, err := ratelimit.NewFromConfig(conf.RateLimit, redisRepo.Client())
+	if err != nil {
+		fatal(log, "failed to create rate limiter", err)
+	}
+
 	//слой авторизации auth
-	userRepository := users.NewUserRepository(db_pg)
-	authService := auth.NewAuthService(userRepository, redisRepo)
-	authHandler := auth.NewAuthHandler(authService, conf)
+	userRepository := users.NewUserRepository(db_pg, log)
+	sessionRepository := sessions.NewSessionRepository(db_pg, log)
+	authService := auth.NewAuthService(userRepository, sessionRepository, redisRepo, tokens, mail, conf, log)
+	authHandler := auth.NewAuthHandler(authService, conf, tokens, log)
+	// письма сброса пароля отправляются в фоне: дожидаемся их до закрытия Postgres
+	lifecycle.Append(Hook{
+		Name:   "auth background tasks",
+		Phase:  PhaseWorkers,
+		OnStop: authService.Wait,
+	})
 
 	//слой продукции match
-	matchRepository := match.NewMatchRepository(db_pg)
-	matchService := match.NewMatchService(matchRepository)
+	matchRepository := match.NewMatchRepository(db_pg, log)
+	matchService := match.NewMatchService(matchRepository, match.NewScorer(conf.Match, log))
 	matchHandler := match.NewMatchHandler(matchService, conf)
 
 	//слой заказов profile
-	profileRepository := profile.NewProfileRepository(db_pg)
+	profileRepository := profile.NewProfileRepository(db_pg, log)
 	profileService := profile.NewProfileService(profileRepository, userRepository)
 	ordersHandler := profile.NewProfileHandler(profileService)
 
-	// создаём экземпляр роутера
-	router := gin.Default()
+	// создаём экземпляр роутера: request id, лог запросов, восстановление после паники
+	router := gin.New()
+	// сервисы получают *gin.Context как context.Context: поля лога запроса берутся из контекста c.Request
+	router.ContextWithFallback = true
+	router.Use(middleware.RequestIDMiddleware(), middleware.RequestLoggerMiddleware(log), gin.Recovery())
 
-	return &Server{
+	server := &Server{
 		router: router,
 		routes: &Routes{
-			Auth:    authHandler,
-			Match:   matchHandler,
-			Profile: ordersHandler,
-			Config:  conf,
+			Auth:        authHandler,
+			Match:       matchHandler,
+			Profile:     ordersHandler,
+			Config:      conf,
+			Permissions: permissions,
+			Tokens:      authService,
+			JWT:         tokens,
+			Limiter:     limiter,
+			Health:      probes,
+			Log:         log,
 		},
 		config: conf,
 		db:     db_pg,
+		log:    log,
+		errs:   make(chan error, 1),
 	}
+	lifecycle.Append(Hook{
+		Name:    "http",
+		Phase:   PhaseHTTP,
+		OnStart: server.Start,
+		OnStop:  server.Shutdown,
+	})
+	// регистрируется после HTTP, поэтому останавливается раньше: /readyz отвечает 503,
+	// пока балансировщик не уберёт экземпляр, и только потом HTTP перестаёт принимать соединения
+	lifecycle.Append(Hook{
+		Name:  "readiness",
+		Phase: PhaseHTTP,
+		OnStop: func(ctx context.Context) error {
+			probes.SetDraining()
+			select {
+			case <-time.After(conf.HTTP.DrainDelay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+	return server
 }
 
 // Метод для маршрутизации сервера
 func (s *Server) SetUpRoutes() {
 	s.router.SetTrustedProxies(nil)
-
-	// Добавляем middleware для проброса контекста
-	s.router.Use(func(c *gin.Context) {
-		ctx := context.WithValue(c.Request.Context(), "request_id", c.GetHeader("X-Request-ID"))
-		c.Request = c.Request.WithContext(ctx)
-		c.Next()
-	})
-
 	s.routes.Setup(s.router)
 }
 
-// Метод для запуска сервера
-func (s *Server) Run() error {
+// Запуск HTTP сервера: адрес занимается сразу (ошибка видна при запуске), запросы обрабатываются
+// в отдельной горутине, её ошибки - в канале Errors
+func (s *Server) Start(ctx context.Context) error {
 	s.SetUpRoutes()
 
+	conf := s.config.HTTP
 	s.httpServer = &http.Server{
-		Addr:    ":8080",
-		Handler: s.router,
+		Addr:              conf.Addr,
+		Handler:           s.router,
+		ReadHeaderTimeout: conf.ReadHeaderTimeout,
+		ReadTimeout:       conf.ReadTimeout,
+		WriteTimeout:      conf.WriteTimeout,
+		IdleTimeout:       conf.IdleTimeout,
+		MaxHeaderBytes:    conf.MaxHeaderBytes,
+	}
+
+	// HTTPS, сертификат перечитывается при изменении файлов (продление без перезапуска)
+	if conf.TLSCertFile != "" {
+		certs, err := tlscert.NewReloader(conf.TLSCertFile, conf.TLSKeyFile, conf.TLSReloadInterval, s.log)
+		if err != nil {
+			return err
+		}
+		s.httpServer.TLSConfig = certs.TLSConfig()
 	}
-	log.Println("Server is running on port 8080")
-	return s.httpServer.ListenAndServe()
+
+	listener, err := net.Listen("tcp", conf.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		var err error
+		if s.httpServer.TLSConfig != nil {
+			s.log.Info("server is running", "addr", listener.Addr().String(), "tls", true)
+			err = s.httpServer.ServeTLS(listener, "", "")
+		} else {
+			s.log.Info("server is running", "addr", listener.Addr().String(), "tls", false)
+			err = s.httpServer.Serve(listener)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			s.errs <- err
+		}
+	}()
+	return nil
 }
 
-// Метод для graceful shutdown
-func (s *Server) Shutdown(ctx context.Context) error {
-	// Закрываем соединение с БД
-	s.db.Close()
+// Ошибки работы HTTP сервера (после успешного Start)
+func (s *Server) Errors() <-chan error {
+	return s.errs
+}
 
-	// Останавливаем HTTP сервер
+// Graceful shutdown HTTP сервера: новые соединения не принимаются, активные запросы завершаются
+// (соединения с БД и Redis закрываются позже, в своей фазе lifecycle)
+func (s *Server) Shutdown(ctx context.Context) error {
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		return err
 	}
 
-	log.Println("Server shutdown completed")
+	s.log.Info("HTTP server stopped")
 	return nil
 }
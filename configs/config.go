@@ -1,60 +1,588 @@
 package configs
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Db    DbConfig   // postgres config
-	Redis RdConfig   // redis config
-	Auth  AuthConfig // auth jwt config
+	HTTP  HTTPConfig  `yaml:"http"`  // HTTP сервер
+	Log   LogConfig   `yaml:"log"`   // логирование
+	Db    DbConfig    `yaml:"db"`    // postgres config
+	Redis RdConfig    `yaml:"redis"` // redis config
+	Auth  AuthConfig  `yaml:"auth"`  // auth jwt config
+	Match MatchConfig `yaml:"match"` // match compatibility scoring config
+	Mail  MailConfig  `yaml:"mail"`  // отправка писем пользователям
+
+	RateLimit RateLimitConfig `yaml:"rate_limit"` // ограничение частоты запросов
+}
+
+type HTTPConfig struct {
+	Addr              string        `yaml:"addr"`                // адрес прослушивания (":8080")
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"` // время на чтение заголовков запроса (защита от slowloris)
+	ReadTimeout       time.Duration `yaml:"read_timeout"`        // время на чтение всего запроса вместе с телом
+	WriteTimeout      time.Duration `yaml:"write_timeout"`       // время на запись ответа (0 - без ограничения)
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`        // время простоя keep-alive соединения
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`    // максимальный размер заголовков запроса
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`    // время на завершение активных запросов при остановке
+	DrainDelay        time.Duration `yaml:"drain_delay"`         // сколько /readyz отвечает 503 перед остановкой HTTP (балансировщик успевает убрать экземпляр)
+	ReadinessTimeout  time.Duration `yaml:"readiness_timeout"`   // таймаут проверки каждой зависимости в /readyz
+
+	TLSCertFile       string        `yaml:"tls_cert_file"`       // PEM сертификат, вместе с TLSKeyFile включает HTTPS
+	TLSKeyFile        string        `yaml:"tls_key_file"`        // PEM приватный ключ сертификата
+	TLSReloadInterval time.Duration `yaml:"tls_reload_interval"` // как часто проверять изменение файлов сертификата
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`  // "debug", "info", "warn", "error"
+	Format string `yaml:"format"` // "text" или "json" (сбор логов в проде)
 }
 
 type DbConfig struct {
-	Dsn string
+	Dsn string `yaml:"dsn"`
 }
 
 type RdConfig struct {
-	Addr string // redis addr from docker-compose
-	Pass string // password
-	NDB  string // BD number
+	Addr string `yaml:"addr"` // redis addr from docker-compose
+	Pass string `yaml:"pass"` // password
+	NDB  string `yaml:"db"`   // BD number
 }
 
 type AuthConfig struct {
-	SecretAcc       string // secret for access token
-	SecretRef       string // secret for refresh token
-	AccessTokenExp  time.Duration
-	RefreshTokenExp time.Duration
+	SecretAcc       string        `yaml:"secret_acc"`        // secret for access token
+	SecretRef       string        `yaml:"secret_ref"`        // secret for refresh token
+	SigningAlg      string        `yaml:"signing_alg"`       // алгоритм подписи access токенов: "HS256" (секрет SecretAcc), "RS256", "EdDSA"
+	KeyID           string        `yaml:"key_id"`            // kid ключа подписи access токенов
+	PrivateKeyFile  string        `yaml:"private_key_file"`  // PEM файл приватного ключа подписи access токенов (для RS256 и EdDSA)
+	VerifyKeyFiles  []string      `yaml:"verify_key_files"`  // предыдущие ключи после ротации, только для проверки: "kid=путь к PEM"
+	Issuer          string        `yaml:"issuer"`            // iss выдаваемых и принимаемых токенов
+	Audience        string        `yaml:"audience"`          // aud выдаваемых и принимаемых токенов ("" - не проверяется)
+	AccessTokenExp  time.Duration `yaml:"access_token_ttl"`  // время жизни access токена
+	RefreshTokenExp time.Duration `yaml:"refresh_token_ttl"` // время жизни refresh токена (сессии)
+	VerifyTokenExp  time.Duration `yaml:"verify_token_ttl"`  // время жизни ссылки подтверждения email
+	ResetTokenExp   time.Duration `yaml:"reset_token_ttl"`   // время жизни токена сброса пароля
+	LocalCacheTTL   time.Duration `yaml:"local_cache_ttl"`   // время жизни in-process кэша проверок отзыва токенов (перед Redis)
+
+	// защита логина от перебора паролей
+	LoginMaxAttempts      int           `yaml:"login_max_attempts"`        // неудачных попыток на один email до блокировки
+	LoginMaxAttemptsPerIP int           `yaml:"login_max_attempts_per_ip"` // неудачных попыток с одного IP до блокировки
+	LoginAttemptWindow    time.Duration `yaml:"login_attempt_window"`      // счётчик неудачных попыток сбрасывается после этого времени без ошибок
+	LoginLockout          time.Duration `yaml:"login_lockout"`             // первая блокировка, каждая следующая в течение суток вдвое дольше
+	LoginMaxLockout       time.Duration `yaml:"login_max_lockout"`         // максимальная длительность блокировки
+}
+
+type MailConfig struct {
+	From     string `yaml:"from"`      // адрес отправителя
+	File     string `yaml:"file"`      // файл, в который дописываются письма (локальная разработка), "" - письма выводятся в лог
+	BaseURL  string `yaml:"base_url"`  // внешний адрес сервиса для ссылок в письмах
+	ResetURL string `yaml:"reset_url"` // страница фронтенда с формой нового пароля, ссылка в письме - reset_url?token=...
+}
+
+type RateLimitConfig struct {
+	Backend string        `yaml:"backend"` // "redis" (общий лимит для всех экземпляров) или "memory"
+	Public  RateLimitRule `yaml:"public"`  // публичные эндпоинты (регистрация, логин, сброс пароля), по IP
+	Default RateLimitRule `yaml:"default"` // эндпоинты авторизованных пользователей, по id пользователя
+	Search  RateLimitRule `yaml:"search"`  // поиск совпадений, по id пользователя
+	Refresh RateLimitRule `yaml:"refresh"` // обновление access токена по refresh токену, по IP (клиенты обновляют токены постоянно)
+}
+
+// Лимит запросов: Requests запросов за Period, подряд - не больше Burst (0 - равен Requests)
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+	Burst    int           `yaml:"burst"`
+}
+
+type MatchConfig struct {
+	Scorer  string       `yaml:"scorer"` // имя алгоритма подсчёта совместимости ("weighted", "hobbies")
+	Weights MatchWeights `yaml:"weights"`
+}
+
+// Веса полей профиля при подсчёте совместимости
+type MatchWeights struct {
+	Hobbies  float64 `yaml:"hobbies"`
+	City     float64 `yaml:"city"`
+	Goal     float64 `yaml:"goal"`
+	Smoking  float64 `yaml:"smoking"`
+	AgeGroup float64 `yaml:"age_group"`
 }
 
+// Настройки HTTP сервера по умолчанию
+const (
+	defaultHTTPAddr          = ":8080"
+	timeHTTPReadHeader       = time.Second * 5
+	timeHTTPRead             = time.Second * 15
+	timeHTTPWrite            = time.Second * 30
+	timeHTTPIdle             = time.Minute
+	timeHTTPShutdown         = time.Second * 10
+	timeHTTPDrain            = time.Second * 5 // укладывается в timeHTTPShutdown вместе с остановкой HTTP
+	timeReadinessCheck       = time.Second * 2
+	timeTLSReload            = time.Minute
+	defaultHTTPMaxHeaderSize = 1 << 20
+)
+
 const (
 	timeExpAccessToken  = time.Minute * 15
 	timeExpRefreshToken = time.Hour * 24
+	timeLocalCache      = time.Second * 5
+	timeExpVerifyToken  = time.Hour * 24
+	timeExpResetToken   = time.Hour
+	timeLoginWindow     = time.Minute * 15
+	timeLoginLockout    = time.Minute
+	timeLoginMaxLockout = time.Hour
+	defaultLoginMax     = 5
+	defaultLoginMaxIP   = 20
+	defaultRateBackend  = "redis"
+	defaultRedisDB      = "0"
+	defaultSigningAlg   = "HS256"
+	defaultIssuer       = "my_app"
+	defaultMailFrom     = "noreply@localhost"
+	defaultBaseURL      = "http://localhost:8080"
+	defaultResetURL     = "http://localhost:3000/reset-password"
+	defaultEnvFile      = ".env"
+	defaultLogLevel     = "info"
+	defaultLogFormat    = "text"
 )
 
-func LoadConfig() *Config {
-	err := godotenv.Load("c:\\Son_Alex\\Go_projects\\e-commerce_proj\\VVV\\V2_mobile\\wisp\\backend\\simple_gin_server\\.env")
-	if err != nil {
-		fmt.Println("Error loading .env file, using default config", err.Error())
-	}
+// Веса совместимости по умолчанию
+const (
+	defaultMatchScorer    = "weighted"
+	defaultWeightHobbies  = 0.4
+	defaultWeightCity     = 0.2
+	defaultWeightGoal     = 0.2
+	defaultWeightSmoking  = 0.1
+	defaultWeightAgeGroup = 0.1
+)
+
+// Источники конфигурации, кроме значений по умолчанию
+type LoadOptions struct {
+	File      string // YAML или TOML файл конфигурации ("" - не используется)
+	EnvFile   string // .env файл, переменные из него не перекрывают уже заданные ("" - ".env", отсутствие файла не ошибка)
+	EnvPrefix string // префикс переменных окружения: "WISP_" - DSN читается из WISP_DSN
+}
+
+// Значения по умолчанию
+func Default() *Config {
 	return &Config{
-		Db: DbConfig{
-			Dsn: os.Getenv("DSN"),
+		HTTP: HTTPConfig{
+			Addr:              defaultHTTPAddr,
+			ReadHeaderTimeout: timeHTTPReadHeader,
+			ReadTimeout:       timeHTTPRead,
+			WriteTimeout:      timeHTTPWrite,
+			IdleTimeout:       timeHTTPIdle,
+			MaxHeaderBytes:    defaultHTTPMaxHeaderSize,
+			ShutdownTimeout:   timeHTTPShutdown,
+			DrainDelay:        timeHTTPDrain,
+			ReadinessTimeout:  timeReadinessCheck,
+			TLSReloadInterval: timeTLSReload,
+		},
+		Log: LogConfig{
+			Level:  defaultLogLevel,
+			Format: defaultLogFormat,
+		},
+		Redis: RdConfig{
+			NDB: defaultRedisDB,
 		},
 		Auth: AuthConfig{
-			SecretAcc:       os.Getenv("JWT_ACC_SECRET"),
-			SecretRef:       os.Getenv("JWT_REF_SECRET"),
+			SigningAlg:      defaultSigningAlg,
+			Issuer:          defaultIssuer,
 			AccessTokenExp:  timeExpAccessToken,
 			RefreshTokenExp: timeExpRefreshToken,
+			VerifyTokenExp:  timeExpVerifyToken,
+			ResetTokenExp:   timeExpResetToken,
+			LocalCacheTTL:   timeLocalCache,
+
+			LoginMaxAttempts:      defaultLoginMax,
+			LoginMaxAttemptsPerIP: defaultLoginMaxIP,
+			LoginAttemptWindow:    timeLoginWindow,
+			LoginLockout:          timeLoginLockout,
+			LoginMaxLockout:       timeLoginMaxLockout,
 		},
-		Redis: RdConfig{
-			Addr: os.Getenv("REDDIS_ADDR"),
-			Pass: os.Getenv("REDDIS_PASS"),
-			NDB:  os.Getenv("REDDIS_BD"),
+		Mail: MailConfig{
+			From:     defaultMailFrom,
+			BaseURL:  defaultBaseURL,
+			ResetURL: defaultResetURL,
 		},
+		RateLimit: RateLimitConfig{
+			Backend: defaultRateBackend,
+			Public:  RateLimitRule{Requests: 10, Period: time.Minute},
+			Default: RateLimitRule{Requests: 120, Period: time.Minute},
+			Search:  RateLimitRule{Requests: 30, Period: time.Minute},
+			Refresh: RateLimitRule{Requests: 60, Period: time.Minute, Burst: 20},
+		},
+		Match: MatchConfig{
+			Scorer: defaultMatchScorer,
+			Weights: MatchWeights{
+				Hobbies:  defaultWeightHobbies,
+				City:     defaultWeightCity,
+				Goal:     defaultWeightGoal,
+				Smoking:  defaultWeightSmoking,
+				AgeGroup: defaultWeightAgeGroup,
+			},
+		},
+	}
+}
+
+// Загрузка конфига по слоям: значения по умолчанию, затем YAML/TOML файл, затем переменные окружения.
+// Проверка значений - Validate
+func Load(opts LoadOptions) (*Config, error) {
+	conf, err := load(opts)
+	if err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// Конфиг из .env и переменных окружения без проверки (для тестов и утилит).
+// Ошибки разбора выводятся, для таких значений остаются значения по умолчанию
+func LoadConfig() *Config {
+	conf, err := load(LoadOptions{})
+	if err != nil {
+		fmt.Println("Config error, using defaults:", err)
+	}
+	return conf
+}
+
+// загрузка по слоям, конфиг возвращается и при ошибке (в нём корректно разобранные значения)
+func load(opts LoadOptions) (*Config, error) {
+	conf := Default()
+
+	envFile := opts.EnvFile
+	if envFile == "" {
+		envFile = defaultEnvFile
+	}
+	if err := godotenv.Load(envFile); err != nil && (opts.EnvFile != "" || !errors.Is(err, fs.ErrNotExist)) {
+		return conf, fmt.Errorf("load %s: %w", envFile, err)
+	}
+
+	if opts.File != "" {
+		if err := conf.loadFile(opts.File); err != nil {
+			return conf, err
+		}
+	}
+
+	env := &envSource{prefix: opts.EnvPrefix}
+	conf.applyEnv(env)
+	return conf, errors.Join(env.errs...)
+}
+
+// перекрывает значения конфига значениями из YAML или TOML файла (отсутствующие в файле поля не меняются)
+func (c *Config) loadFile(path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".yaml", ".yml", ".toml":
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q, expected .yaml, .yml or .toml", path, ext)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	if ext == ".toml" {
+		// TOML переводится в YAML: те же имена полей, длительности строками ("5s") и проверка неизвестных полей
+		var raw map[string]any
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		if data, err = yaml.Marshal(raw); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // опечатка в имени поля - ошибка, а не молча пропущенная настройка
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// перекрывает значения конфига заданными переменными окружения
+func (c *Config) applyEnv(env *envSource) {
+	env.str("HTTP_ADDR", &c.HTTP.Addr)
+	env.duration("HTTP_READ_HEADER_TIMEOUT", &c.HTTP.ReadHeaderTimeout)
+	env.duration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout)
+	env.duration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout)
+	env.duration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout)
+	env.int("HTTP_MAX_HEADER_BYTES", &c.HTTP.MaxHeaderBytes)
+	env.duration("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout)
+	env.duration("HTTP_DRAIN_DELAY", &c.HTTP.DrainDelay)
+	env.duration("HTTP_READINESS_TIMEOUT", &c.HTTP.ReadinessTimeout)
+	env.str("TLS_CERT_FILE", &c.HTTP.TLSCertFile)
+	env.str("TLS_KEY_FILE", &c.HTTP.TLSKeyFile)
+	env.duration("TLS_RELOAD_INTERVAL", &c.HTTP.TLSReloadInterval)
+
+	env.str("LOG_LEVEL", &c.Log.Level)
+	env.str("LOG_FORMAT", &c.Log.Format)
+
+	env.str("DSN", &c.Db.Dsn)
+
+	env.str("JWT_ACC_SECRET", &c.Auth.SecretAcc)
+	env.str("JWT_REF_SECRET", &c.Auth.SecretRef)
+	env.str("JWT_ALG", &c.Auth.SigningAlg)
+	env.str("JWT_KEY_ID", &c.Auth.KeyID)
+	env.str("JWT_PRIVATE_KEY_FILE", &c.Auth.PrivateKeyFile)
+	env.list("JWT_VERIFY_KEY_FILES", &c.Auth.VerifyKeyFiles)
+	env.str("JWT_ISSUER", &c.Auth.Issuer)
+	env.str("JWT_AUDIENCE", &c.Auth.Audience)
+	env.duration("AUTH_ACCESS_TOKEN_TTL", &c.Auth.AccessTokenExp)
+	env.duration("AUTH_REFRESH_TOKEN_TTL", &c.Auth.RefreshTokenExp)
+	env.duration("AUTH_VERIFY_TOKEN_TTL", &c.Auth.VerifyTokenExp)
+	env.duration("AUTH_RESET_TOKEN_TTL", &c.Auth.ResetTokenExp)
+	env.duration("AUTH_LOCAL_CACHE_TTL", &c.Auth.LocalCacheTTL)
+
+	env.int("LOGIN_MAX_ATTEMPTS", &c.Auth.LoginMaxAttempts)
+	env.int("LOGIN_MAX_ATTEMPTS_PER_IP", &c.Auth.LoginMaxAttemptsPerIP)
+	env.duration("LOGIN_ATTEMPT_WINDOW", &c.Auth.LoginAttemptWindow)
+	env.duration("LOGIN_LOCKOUT", &c.Auth.LoginLockout)
+	env.duration("LOGIN_MAX_LOCKOUT", &c.Auth.LoginMaxLockout)
+
+	env.str("REDDIS_ADDR", &c.Redis.Addr)
+	env.str("REDDIS_PASS", &c.Redis.Pass)
+	env.str("REDDIS_BD", &c.Redis.NDB)
+
+	env.str("MAIL_FROM", &c.Mail.From)
+	env.str("MAIL_FILE", &c.Mail.File)
+	env.str("APP_BASE_URL", &c.Mail.BaseURL)
+	env.str("MAIL_RESET_URL", &c.Mail.ResetURL)
+
+	env.str("RATE_LIMIT_BACKEND", &c.RateLimit.Backend)
+	env.rate("RATE_LIMIT_PUBLIC", &c.RateLimit.Public)
+	env.rate("RATE_LIMIT_DEFAULT", &c.RateLimit.Default)
+	env.rate("RATE_LIMIT_SEARCH", &c.RateLimit.Search)
+	env.rate("RATE_LIMIT_REFRESH", &c.RateLimit.Refresh)
+
+	env.str("MATCH_SCORER", &c.Match.Scorer)
+	env.float("MATCH_WEIGHT_HOBBIES", &c.Match.Weights.Hobbies)
+	env.float("MATCH_WEIGHT_CITY", &c.Match.Weights.City)
+	env.float("MATCH_WEIGHT_GOAL", &c.Match.Weights.Goal)
+	env.float("MATCH_WEIGHT_SMOKING", &c.Match.Weights.Smoking)
+	env.float("MATCH_WEIGHT_AGE_GROUP", &c.Match.Weights.AgeGroup)
+}
+
+// Проверка конфига перед запуском сервера, возвращает все найденные ошибки
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Addr != "", "http.addr (HTTP_ADDR) is required")
+	for _, timeout := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"http.read_header_timeout", c.HTTP.ReadHeaderTimeout},
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout", c.HTTP.ShutdownTimeout},
+		{"http.readiness_timeout", c.HTTP.ReadinessTimeout},
+	} {
+		check(timeout.d > 0, "%s must be positive, got %v", timeout.name, timeout.d)
+	}
+	check(c.HTTP.WriteTimeout >= 0, "http.write_timeout must not be negative, got %v", c.HTTP.WriteTimeout)
+	check(c.HTTP.DrainDelay >= 0 && c.HTTP.DrainDelay < c.HTTP.ShutdownTimeout, "http.drain_delay must be shorter than http.shutdown_timeout, got %v", c.HTTP.DrainDelay)
+	check(c.HTTP.MaxHeaderBytes > 0, "http.max_header_bytes must be positive, got %d", c.HTTP.MaxHeaderBytes)
+	check((c.HTTP.TLSCertFile == "") == (c.HTTP.TLSKeyFile == ""), "http.tls_cert_file and http.tls_key_file must be set together")
+	check(c.HTTP.TLSCertFile == "" || c.HTTP.TLSReloadInterval > 0, "http.tls_reload_interval must be positive, got %v", c.HTTP.TLSReloadInterval)
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level (LOG_LEVEL) must be debug, info, warn or error, got %q", c.Log.Level)
+	check(c.Log.Format == "text" || c.Log.Format == "json", "log.format (LOG_FORMAT) must be \"text\" or \"json\", got %q", c.Log.Format)
+
+	check(c.Db.Dsn != "", "db.dsn (DSN) is required")
+
+	db, err := strconv.Atoi(c.Redis.NDB)
+	check(err == nil && db >= 0, "redis.db (REDDIS_BD) must be a non-negative integer, got %q", c.Redis.NDB)
+
+	switch c.Auth.SigningAlg {
+	case "HS256":
+		check(c.Auth.SecretAcc != "", "auth.secret_acc (JWT_ACC_SECRET) is required for HS256")
+	case "RS256", "EdDSA":
+		// секрет HMAC не нужен, подпись приватным ключом
+		check(c.Auth.KeyID != "", "auth.key_id (JWT_KEY_ID) is required for %s", c.Auth.SigningAlg)
+		check(c.Auth.PrivateKeyFile != "", "auth.private_key_file (JWT_PRIVATE_KEY_FILE) is required for %s", c.Auth.SigningAlg)
+	default:
+		check(false, "auth.signing_alg (JWT_ALG) must be \"HS256\", \"RS256\" or \"EdDSA\", got %q", c.Auth.SigningAlg)
+	}
+	check(c.Auth.SecretRef != "", "auth.secret_ref (JWT_REF_SECRET) is required")
+	for _, ttl := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"auth.access_token_ttl", c.Auth.AccessTokenExp},
+		{"auth.refresh_token_ttl", c.Auth.RefreshTokenExp},
+		{"auth.verify_token_ttl", c.Auth.VerifyTokenExp},
+		{"auth.reset_token_ttl", c.Auth.ResetTokenExp},
+		{"auth.local_cache_ttl", c.Auth.LocalCacheTTL},
+		{"auth.login_attempt_window", c.Auth.LoginAttemptWindow},
+		{"auth.login_lockout", c.Auth.LoginLockout},
+	} {
+		check(ttl.d > 0, "%s must be positive, got %v", ttl.name, ttl.d)
+	}
+	check(c.Auth.AccessTokenExp < c.Auth.RefreshTokenExp, "auth.access_token_ttl must be shorter than auth.refresh_token_ttl")
+	check(c.Auth.LoginMaxLockout >= c.Auth.LoginLockout, "auth.login_max_lockout must not be shorter than auth.login_lockout")
+	check(c.Auth.LoginMaxAttempts > 0 && c.Auth.LoginMaxAttemptsPerIP > 0, "auth.login_max_attempts and auth.login_max_attempts_per_ip must be positive")
+
+	for _, weight := range []struct {
+		name string
+		w    float64
+	}{
+		{"match.weights.hobbies", c.Match.Weights.Hobbies},
+		{"match.weights.city", c.Match.Weights.City},
+		{"match.weights.goal", c.Match.Weights.Goal},
+		{"match.weights.smoking", c.Match.Weights.Smoking},
+		{"match.weights.age_group", c.Match.Weights.AgeGroup},
+	} {
+		check(weight.w >= 0, "%s must not be negative, got %v", weight.name, weight.w)
+	}
+
+	resetURL, err := url.Parse(c.Mail.ResetURL)
+	check(err == nil && resetURL.Scheme != "" && resetURL.Host != "", "mail.reset_url (MAIL_RESET_URL) must be an absolute URL, got %q", c.Mail.ResetURL)
+
+	check(c.RateLimit.Backend == "redis" || c.RateLimit.Backend == "memory", "rate_limit.backend must be \"redis\" or \"memory\", got %q", c.RateLimit.Backend)
+	for _, limit := range []struct {
+		name string
+		rule RateLimitRule
+	}{
+		{"rate_limit.public", c.RateLimit.Public},
+		{"rate_limit.default", c.RateLimit.Default},
+		{"rate_limit.search", c.RateLimit.Search},
+		{"rate_limit.refresh", c.RateLimit.Refresh},
+	} {
+		check(limit.rule.Requests > 0 && limit.rule.Period > 0 && limit.rule.Burst >= 0, "%s must have positive requests and period, got %+v", limit.name, limit.rule)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Переменные окружения с необязательным префиксом, ошибки разбора накапливаются
+type envSource struct {
+	prefix string
+	errs   []error
+}
+
+// значение переменной окружения, пустое значение считается незаданным
+func (e *envSource) lookup(key string) (string, bool) {
+	val := os.Getenv(e.prefix + key)
+	return val, val != ""
+}
+
+func (e *envSource) invalid(key, val string, err error) {
+	e.errs = append(e.errs, fmt.Errorf("%s%s=%q: %w", e.prefix, key, val, err))
+}
+
+func (e *envSource) str(key string, dst *string) {
+	if val, ok := e.lookup(key); ok {
+		*dst = val
+	}
+}
+
+func (e *envSource) int(key string, dst *int) {
+	val, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		e.invalid(key, val, err)
+		return
+	}
+	*dst = i
+}
+
+func (e *envSource) float(key string, dst *float64) {
+	val, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		e.invalid(key, val, err)
+		return
+	}
+	*dst = f
+}
+
+// time.Duration в формате "5s", "1m"
+func (e *envSource) duration(key string, dst *time.Duration) {
+	val, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		e.invalid(key, val, err)
+		return
+	}
+	*dst = d
+}
+
+// лимит запросов "запросов/период[/burst]" ("10/1m", "100/1h/20")
+func (e *envSource) rate(key string, dst *RateLimitRule) {
+	val, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	rule, err := parseRate(val)
+	if err != nil {
+		e.invalid(key, val, err)
+		return
+	}
+	*dst = rule
+}
+
+// список через запятую (пустые элементы пропускаются)
+func (e *envSource) list(key string, dst *[]string) {
+	val, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	var list []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*dst = list
+}
+
+func parseRate(val string) (RateLimitRule, error) {
+	parts := strings.Split(val, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return RateLimitRule{}, errors.New("expected requests/period[/burst]")
+	}
+
+	var rule RateLimitRule
+	var err error
+	if rule.Requests, err = strconv.Atoi(parts[0]); err != nil || rule.Requests <= 0 {
+		return RateLimitRule{}, errors.New("requests must be a positive integer")
+	}
+	if rule.Period, err = time.ParseDuration(parts[1]); err != nil || rule.Period <= 0 {
+		return RateLimitRule{}, errors.New("period must be a positive duration")
+	}
+	if len(parts) == 3 {
+		if rule.Burst, err = strconv.Atoi(parts[2]); err != nil || rule.Burst < 0 {
+			return RateLimitRule{}, errors.New("burst must be a non-negative integer")
+		}
 	}
+	return rule, nil
 }
@@ -0,0 +1,158 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// пишет файл во временную директорию теста
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// тест слоёв: значения по умолчанию, поверх них YAML файл, поверх него переменные окружения с префиксом
+func TestLoad_Layers(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+db:
+  dsn: postgres://file
+auth:
+  secret_acc: file-acc
+  access_token_ttl: 5m
+rate_limit:
+  search: {requests: 5, period: 10s}
+`)
+	t.Setenv("TEST_DSN", "postgres://env")
+	t.Setenv("TEST_AUTH_REFRESH_TOKEN_TTL", "48h")
+	t.Setenv("TEST_HTTP_ADDR", ":9090")
+	t.Setenv("DSN", "postgres://without-prefix")
+
+	conf, err := Load(LoadOptions{File: file, EnvFile: writeFile(t, ".env", ""), EnvPrefix: "TEST_"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "postgres://env", conf.Db.Dsn)
+	assert.Equal(t, "file-acc", conf.Auth.SecretAcc)
+	assert.Equal(t, 5*time.Minute, conf.Auth.AccessTokenExp)
+	assert.Equal(t, 48*time.Hour, conf.Auth.RefreshTokenExp)
+	assert.Equal(t, RateLimitRule{Requests: 5, Period: 10 * time.Second}, conf.RateLimit.Search)
+	assert.Equal(t, Default().RateLimit.Public, conf.RateLimit.Public)
+	assert.Equal(t, defaultIssuer, conf.Auth.Issuer)
+	assert.Equal(t, ":9090", conf.HTTP.Addr)
+	assert.Equal(t, timeHTTPShutdown, conf.HTTP.ShutdownTimeout)
+}
+
+// тест TOML файла: те же имена полей и формат длительностей, что и в YAML
+func TestLoad_TOMLFile(t *testing.T) {
+	file := writeFile(t, "config.toml", `
+[db]
+dsn = "postgres://file"
+
+[auth]
+secret_acc = "file-acc"
+access_token_ttl = "5m"
+
+[rate_limit]
+search = {requests = 5, period = "10s"}
+
+[match.weights]
+city = 0.5
+`)
+
+	conf, err := Load(LoadOptions{File: file, EnvFile: writeFile(t, ".env", "")})
+	require.NoError(t, err)
+
+	assert.Equal(t, "postgres://file", conf.Db.Dsn)
+	assert.Equal(t, "file-acc", conf.Auth.SecretAcc)
+	assert.Equal(t, 5*time.Minute, conf.Auth.AccessTokenExp)
+	assert.Equal(t, RateLimitRule{Requests: 5, Period: 10 * time.Second}, conf.RateLimit.Search)
+	assert.Equal(t, 0.5, conf.Match.Weights.City)
+	assert.Equal(t, Default().Match.Weights.Goal, conf.Match.Weights.Goal)
+
+	_, err = Load(LoadOptions{File: writeFile(t, "typo.toml", "[auth]\nsecret_acess = \"typo\"\n"), EnvFile: writeFile(t, ".env", "")})
+	assert.ErrorContains(t, err, "secret_acess")
+}
+
+// тест ошибок источников: неизвестное поле в файле, неверный формат файла, неразбираемая переменная окружения
+func TestLoad_Errors(t *testing.T) {
+	envFile := writeFile(t, ".env", "")
+
+	_, err := Load(LoadOptions{File: writeFile(t, "config.yaml", "auth:\n  secret_acess: typo\n"), EnvFile: envFile})
+	assert.ErrorContains(t, err, "secret_acess")
+
+	_, err = Load(LoadOptions{File: writeFile(t, "config.json", "{}"), EnvFile: envFile})
+	assert.ErrorContains(t, err, "unsupported format")
+
+	_, err = Load(LoadOptions{File: filepath.Join(t.TempDir(), "missing.yaml"), EnvFile: envFile})
+	assert.Error(t, err)
+
+	t.Setenv("TEST_LOGIN_LOCKOUT", "forever")
+	_, err = Load(LoadOptions{EnvFile: envFile, EnvPrefix: "TEST_"})
+	assert.ErrorContains(t, err, "TEST_LOGIN_LOCKOUT")
+}
+
+// тест проверки конфига перед запуском
+func TestConfig_Validate(t *testing.T) {
+	valid := func() *Config {
+		conf := Default()
+		conf.Db.Dsn = "postgres://localhost"
+		conf.Auth.SecretAcc = "acc"
+		conf.Auth.SecretRef = "ref"
+		return conf
+	}
+	require.NoError(t, valid().Validate())
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{name: "missing dsn", modify: func(c *Config) { c.Db.Dsn = "" }, wantErr: "db.dsn"},
+		{name: "empty access secret", modify: func(c *Config) { c.Auth.SecretAcc = "" }, wantErr: "JWT_ACC_SECRET"},
+		{name: "unknown signing alg", modify: func(c *Config) { c.Auth.SigningAlg = "HS512" }, wantErr: "auth.signing_alg"},
+		{name: "rs256 without private key", modify: func(c *Config) { c.Auth.SigningAlg, c.Auth.KeyID = "RS256", "k1" }, wantErr: "auth.private_key_file"},
+		{name: "empty refresh secret", modify: func(c *Config) { c.Auth.SecretRef = "" }, wantErr: "JWT_REF_SECRET"},
+		{name: "redis db is not a number", modify: func(c *Config) { c.Redis.NDB = "first" }, wantErr: "redis.db"},
+		{name: "zero token ttl", modify: func(c *Config) { c.Auth.AccessTokenExp = 0 }, wantErr: "auth.access_token_ttl"},
+		{name: "zero local cache ttl", modify: func(c *Config) { c.Auth.LocalCacheTTL = 0 }, wantErr: "auth.local_cache_ttl"},
+		{name: "no read header timeout", modify: func(c *Config) { c.HTTP.ReadHeaderTimeout = 0 }, wantErr: "http.read_header_timeout"},
+		{name: "tls cert without key", modify: func(c *Config) { c.HTTP.TLSCertFile = "tls.crt" }, wantErr: "http.tls_key_file"},
+		{name: "drain delay longer than shutdown", modify: func(c *Config) { c.HTTP.DrainDelay = c.HTTP.ShutdownTimeout }, wantErr: "http.drain_delay"},
+		{name: "unknown log format", modify: func(c *Config) { c.Log.Format = "xml" }, wantErr: "log.format"},
+		{name: "negative match weight", modify: func(c *Config) { c.Match.Weights.City = -0.5 }, wantErr: "match.weights.city"},
+		{name: "relative reset page url", modify: func(c *Config) { c.Mail.ResetURL = "/reset-password" }, wantErr: "mail.reset_url"},
+		{name: "unknown rate limit backend", modify: func(c *Config) { c.RateLimit.Backend = "memcached" }, wantErr: "rate_limit.backend"},
+		{name: "empty rate limit rule", modify: func(c *Config) { c.RateLimit.Search = RateLimitRule{} }, wantErr: "rate_limit.search"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := valid()
+			tt.modify(conf)
+			assert.ErrorContains(t, conf.Validate(), tt.wantErr)
+		})
+	}
+
+	t.Run("asymmetric alg without hmac secret", func(t *testing.T) {
+		conf := valid()
+		conf.Auth.SigningAlg, conf.Auth.KeyID, conf.Auth.PrivateKeyFile = "EdDSA", "k1", "keys/ed25519.pem"
+		conf.Auth.SecretAcc = ""
+		assert.NoError(t, conf.Validate())
+	})
+}
+
+// тест примера конфигурации: файл разбирается и после задания секретов проходит проверку
+func TestLoad_ExampleFile(t *testing.T) {
+	conf, err := Load(LoadOptions{File: "config.example.yaml", EnvFile: writeFile(t, ".env", "")})
+	require.NoError(t, err)
+
+	conf.Auth.SecretAcc, conf.Auth.SecretRef = "acc", "ref"
+	assert.NoError(t, conf.Validate())
+	assert.Equal(t, 10, conf.RateLimit.Search.Burst)
+	assert.Equal(t, 5*time.Second, conf.HTTP.ReadHeaderTimeout)
+}
@@ -0,0 +1,26 @@
+package auth
+
+import "errors"
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenRevoked = errors.New("token revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, the session has been revoked")
+
+	ErrEmailNotVerified         = errors.New("email is not verified")
+	ErrAccountDeactivated       = errors.New("account is deactivated")
+	ErrInvalidVerificationToken = errors.New("invalid or already used verification link")
+	ErrVerificationTokenExpired = errors.New("verification link has expired")
+
+	ErrInvalidResetToken = errors.New("invalid or already used password reset token")
+	ErrResetTokenExpired = errors.New("password reset token has expired")
+
+	ErrEmailRequired         = errors.New("email is required")
+	ErrInvalidEmail          = errors.New("invalid email format")
+	ErrPasswordTooShort      = errors.New("password must be at least 8 characters")
+	ErrPasswordNoSpecialChar = errors.New("password must contain special character")
+	ErrWrongPassword         = errors.New("current password is incorrect")
+	ErrSamePassword          = errors.New("new password must differ from the current one")
+
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts")
+)
@@ -1,26 +1,34 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"simple_gin_server/configs"
+	"simple_gin_server/internal/sessions"
+	"simple_gin_server/internal/users"
 	"simple_gin_server/pkg/jwt_stuff"
+	"simple_gin_server/pkg/middleware"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthHandler struct {
 	service ServiceInterface
 	config  *configs.Config
+	tokens  *jwt_stuff.JWT
+	log     *slog.Logger
 }
 
-func NewAuthHandler(service ServiceInterface, config *configs.Config) *AuthHandler {
+func NewAuthHandler(service ServiceInterface, config *configs.Config, tokens *jwt_stuff.JWT, log *slog.Logger) *AuthHandler {
 	return &AuthHandler{
 		service: service,
 		config:  config,
+		tokens:  tokens,
+		log:     log,
 	}
 }
 
@@ -46,10 +54,16 @@ func (h *AuthHandler) RegisterHandler(c *gin.Context) {
 		return
 	}
 
+	// требования к email и паролю
+	if err := validateCredentials(user.Email, user.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	//пробуем регистрировать пользователя
 	err := h.service.Register(c, user.Email, user.Password)
 	if err != nil {
-		log.Println("Error during attempt of registration")
+		h.log.ErrorContext(c, "registration failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -80,21 +94,22 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 	//log.Printf("password from login request: %v", user.Password)
 
 	//пробуем залогировать пользователя
-	err := h.service.Login(c, user.Email, user.Password)
+	err := h.service.Login(c, user.Email, user.Password, c.ClientIP())
 	if err != nil {
+		var locked *LoginLockedError
+		if errors.As(err, &locked) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": ErrTooManyLoginAttempts.Error()})
+			return
+		}
+		if errors.Is(err, ErrEmailNotVerified) || errors.Is(err, ErrAccountDeactivated) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Генерация JWT токена
-
-	jwtObject := jwt_stuff.NewJWT(
-		h.config.Auth.SecretAcc,
-		h.config.Auth.SecretRef,
-		h.config.Auth.AccessTokenExp,
-		h.config.Auth.RefreshTokenExp,
-	)
-
 	regUser, err := h.service.GetUserByEmail(c, user.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при получении Id зарегестрированного пользователя"})
@@ -102,15 +117,20 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 	}
 
 	//генерируем access и refresh токены
-	accessToken, refreshToken, err := jwtObject.GenerateTokens(user.Email, strconv.Itoa(regUser.Id))
+	accessToken, refreshToken, err := h.tokens.GenerateTokens(user.Email, strconv.Itoa(regUser.Id), regUser.Role, regUser.IsActive, regUser.TokenVersion)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при генерации токена"})
 		return
 	}
 
-	err = h.service.AddRefreshTokenToDb(c, user.Email, refreshToken)
+	// каждый логин - отдельная сессия со своим refresh токеном
+	err = h.service.CreateSession(c, regUser.Id, refreshToken, sessions.ClientInfo{
+		DeviceName: user.DeviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка записи refreshToken в БД"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания сессии"})
 		return
 	}
 
@@ -121,11 +141,139 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 	})
 }
 
+// Хэндлер подтверждения email по ссылке из письма (токен в query параметре token)
+func (h *AuthHandler) VerifyEmailHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	err := h.service.VerifyEmail(c, token)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+	case errors.Is(err, ErrVerificationTokenExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidVerificationToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidVerificationToken.Error()})
+	default:
+		h.log.ErrorContext(c, "email verification failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+	}
+}
+
+// Хэндлер запроса сброса пароля: ответ всегда одинаковый, чтобы нельзя было узнать, зарегистрирован ли email
+func (h *AuthHandler) ForgotPasswordHandler(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Validation data not found"})
+		return
+	}
+
+	req, ok := validatedData.(*ForgotPasswordRequest)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid request type"})
+		return
+	}
+
+	// письмо отправляется в фоне, ошибка возможна только до его запуска (например, отменён запрос)
+	if err := h.service.ForgotPassword(c, req.Email); err != nil {
+		h.log.ErrorContext(c, "password reset request failed", "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the email is registered, a password reset link has been sent"})
+}
+
+// Хэндлер установки нового пароля по токену из письма
+func (h *AuthHandler) ResetPasswordHandler(c *gin.Context) {
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Validation data not found"})
+		return
+	}
+
+	req, ok := validatedData.(*ResetPasswordRequest)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid request type"})
+		return
+	}
+
+	if err := validatePasswordStrength(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.service.ResetPassword(c, req.Token, req.NewPassword)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+	case errors.Is(err, ErrInvalidResetToken), errors.Is(err, ErrResetTokenExpired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.log.ErrorContext(c, "password reset failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+	}
+}
+
+// Хэндлер смены пароля авторизованным пользователем: нужен текущий пароль, остальные сессии пользователя закрываются
+func (h *AuthHandler) ChangePasswordHandler(c *gin.Context) {
+	userId, ok := userIdFromContext(c)
+	if !ok {
+		return
+	}
+
+	validatedData, exists := c.Get("validatedData")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Validation data not found"})
+		return
+	}
+
+	req, ok := validatedData.(*ChangePasswordRequest)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid request type"})
+		return
+	}
+
+	if err := validatePasswordStrength(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// все ранее выданные токены отозваны, текущей сессии выдаётся новая пара
+	accessToken, refreshToken, err := h.service.ChangePassword(c, userId, c.GetString("session_id"), req.CurrentPassword, req.NewPassword, c.ClientIP())
+	var locked *LoginLockedError
+	switch {
+	case err == nil && accessToken == "":
+		c.JSON(http.StatusOK, gin.H{"message": "Password has been changed, log in again"})
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{
+			"message":       "Password has been changed",
+			"access_token":  accessToken,
+			"refresh_token": refreshToken,
+		})
+	case errors.As(err, &locked):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": ErrTooManyLoginAttempts.Error()})
+	case errors.Is(err, ErrWrongPassword), errors.Is(err, ErrSamePassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.log.ErrorContext(c, "password change failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+	}
+}
+
+// Хэндлер публичных ключей проверки access токенов (JWKS), нужен другим сервисам для проверки токенов без секрета
+func (h *AuthHandler) JWKSHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokens.AccessKeys.JWKS())
+}
+
 // Хэндлер получения email зарегестрированных пользователей, доступ к сервиному слою
 func (h *AuthHandler) ListHandler(c *gin.Context) {
 	list, err := h.service.GetUserList(c)
 	if err != nil {
-		log.Println(err.Error())
+		h.log.ErrorContext(c, "failed to get user list", "error", err)
 	}
 	c.JSON(http.StatusOK, gin.H{"user_emails": list})
 }
@@ -150,72 +298,50 @@ func (h *AuthHandler) ProcessRefreshTokenHandler(c *gin.Context) {
 	default:
 	}
 
-	reqRefToken, err := jwt_stuff.ParseTokenWithClaims(c, req.RefreshToken, h.config.Auth.SecretRef)
+	// Проверяем подпись, срок действия, издателя и тип токена
+	claims, err := h.tokens.RefreshVerifier().Verify(ctx, req.RefreshToken)
 	if err != nil {
-		log.Println("Wrong refresh token")
-		return
-	}
-
-	if !reqRefToken.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
-		log.Println("Invalid refresh token")
-		return
-	}
-
-	// Извлекаем claims из токена
-	claims, ok := reqRefToken.Claims.(*jwt_stuff.CustomClaims)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		h.log.InfoContext(c, "invalid refresh token", "error", err)
+		middleware.AbortWithTokenError(c, err)
 		return
 	}
 
-	// Проверяем, что токен является refresh токеном
-	if claims.TokenType != "refresh" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is not a refresh token"})
-		return
-	}
-
-	// проверка в Reddis (черный список)
-	redisKey := fmt.Sprintf("refresh_token:%s", claims.ID) // claims.ID = jti из токена
-	exists, err := h.service.ExistsInBlackList(c, redisKey)
+	// проверка, что токен - текущий токен действующей сессии
+	user, err := h.service.ValidateRefreshToken(c, *claims)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "failed to check token in Redis"})
-		return
-	}
-	if exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "token revoked"}) // Токен в черном списке!
+		h.writeRefreshError(c, err)
 		return
 	}
 
-	// Проверка наличия данного refresh токена в БД
-	user, err := h.service.GetUserByClaims(c, *claims)
+	// Генерация новой пары токенов в том же семействе, роль и активность берём из БД (могли измениться после логина)
+	accessToken, refreshToken, err := h.tokens.GenerateTokensInFamily(claims.Email, claims.UserId, user.Role, user.IsActive, user.TokenVersion, claims.FamilyId)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "user was not found by refresh token claims in BD"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	if user.RefreshToken == "" || user.RefreshToken != req.RefreshToken {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+	// в сессии старый refresh токен заменяется новым
+	if err := h.service.RotateRefreshToken(c, *claims, refreshToken); err != nil {
+		h.writeRefreshError(c, err)
 		return
 	}
 
-	// Извлекаем user Email из claims
-	email := claims.Email
-	userId := claims.ID
-
-	// Создаем новый access токен
-	newAccessTokenClaims := jwt_stuff.NewClaims(h.config.Auth.AccessTokenExp, email, userId, "access", "my_app")
-	newAccessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, newAccessTokenClaims)
+	// Возвращаем новые access и refresh токены
+	c.JSON(http.StatusOK, gin.H{
+		"AccessToken":  accessToken,
+		"RefreshToken": refreshToken,
+	})
+}
 
-	// Подписываем токен
-	accessTokenString, err := newAccessToken.SignedString([]byte(h.config.Auth.SecretAcc))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
-		return
+// пишет ответ на ошибку проверки или ротации refresh токена
+func (h *AuthHandler) writeRefreshError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrRefreshTokenRevoked), errors.Is(err, ErrRefreshTokenReused), errors.Is(err, ErrInvalidRefreshToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		h.log.ErrorContext(c, "refresh token rotation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
 	}
-
-	// Возвращаем новый access токен
-	c.JSON(http.StatusOK, gin.H{"AccessToken": accessTokenString})
 }
 
 // Хэндлер для функции LogOut, инвалидация refresh токена
@@ -238,16 +364,160 @@ func (h *AuthHandler) LogoutHandler(c *gin.Context) {
 	default:
 	}
 
-	// Инвалидируем токен в хранилище (Redis/БД)
-	if err := h.service.InvalidateRefreshToken(c, req.RefreshToken); err != nil {
+	// Проверяем подпись, срок действия, издателя и тип refresh токена
+	claims, err := h.tokens.RefreshVerifier().Verify(ctx, req.RefreshToken)
+	if err != nil {
+		h.log.InfoContext(c, "logout with invalid refresh token", "error", err)
+		middleware.AbortWithTokenError(c, err)
+		return
+	}
+
+	userId, ok := userIdFromContext(c)
+	if !ok {
+		return
+	}
+
+	// access токен, с которым пришёл запрос, перестаёт приниматься сразу, не дожидаясь истечения
+	if jti, expiresAt := c.GetString("token_id"), c.GetTime("token_expires_at"); jti != "" {
+		if err := h.service.RevokeAccessToken(c, jti, expiresAt); err != nil {
+			h.log.ErrorContext(c, "failed to revoke access token", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+			return
+		}
+	}
+
+	// Удаляем сессию, которой принадлежит токен (только среди сессий пользователя из access токена)
+	if err := h.service.InvalidateRefreshToken(c, userId, *claims); err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.ErrorContext(c, "failed to delete session on logout", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"Message": "Session has been closed"})
+}
+
+// Хэндлер logout со всех устройств: отзываются все access и refresh токены пользователя
+func (h *AuthHandler) LogoutAllHandler(c *gin.Context) {
+	userId, ok := userIdFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.LogoutAll(c, userId); err != nil {
+		h.log.ErrorContext(c, "logout from all devices failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"Message": "Refresh token has been added to black list and removed from DB"})
+	c.JSON(http.StatusOK, gin.H{"Message": "All sessions have been closed"})
+}
+
+// Хэндлер снятия блокировки логина пользователя по его ID после неудачных попыток (только для админа)
+func (h *AuthHandler) UnlockUserHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	if err := h.service.UnlockAccount(c, id); err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.ErrorContext(c, "failed to unlock user", "target_user_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"Message": "User login has been unlocked"})
 }
 
 // Хэндлер для удаления юзера по его ID, только с админскими прававами(проверка прав через middleware)
 func (h *AuthHandler) DeleteUserHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	if err := h.service.DeleteUser(c, id); err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.ErrorContext(c, "failed to delete user", "target_user_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"Message": fmt.Sprintf("User with id:%d has been deleted", id)})
+}
+
+// Хэндлер получения списка действующих сессий (устройств) текущего пользователя
+func (h *AuthHandler) ListSessionsHandler(c *gin.Context) {
+	userId, ok := userIdFromContext(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.ListSessions(c, userId)
+	if err != nil {
+		h.log.ErrorContext(c, "failed to list sessions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions"})
+		return
+	}
+
+	if list == nil {
+		list = []sessions.Session{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": list})
+}
+
+// Хэндлер отзыва сессии текущего пользователя по её ID (выход на другом устройстве)
+func (h *AuthHandler) DeleteSessionHandler(c *gin.Context) {
+	userId, ok := userIdFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeSession(c, userId, c.Param("id")); err != nil {
+		if errors.Is(err, sessions.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.log.ErrorContext(c, "failed to revoke session", "session_id", c.Param("id"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"Message": "Session has been revoked"})
+}
+
+// извлекает id авторизованного пользователя из контекста (добавляется в AuthMiddleware), при ошибке сразу пишет ответ
+func userIdFromContext(c *gin.Context) (int, bool) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found"})
+		return 0, false
+	}
+
+	userIdStr, ok := userId.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Wrong User ID type"})
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(userIdStr)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user id in token"})
+		return 0, false
+	}
 
+	return id, true
 }
@@ -1,10 +1,17 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"simple_gin_server/configs"
 	"simple_gin_server/internal/moks"
+	"simple_gin_server/internal/users"
+	"simple_gin_server/pkg/jwt_stuff"
+	"simple_gin_server/pkg/logger"
+	"strconv"
+	"strings"
+	"time"
 
 	"testing"
 
@@ -17,7 +24,8 @@ import (
 func setUpAuthHandlerTest(t *testing.T) (*moks.MockAuthService, *AuthHandler) {
 	mockService := new(moks.MockAuthService)
 	conf := configs.LoadConfig()
-	return mockService, NewAuthHandler(mockService, conf)
+	tokens := jwt_stuff.NewJWT(conf.Auth.SecretAcc, conf.Auth.SecretRef, conf.Auth.AccessTokenExp, conf.Auth.RefreshTokenExp)
+	return mockService, NewAuthHandler(mockService, conf, tokens, logger.Nop())
 }
 
 // тест проверяет "счастливый путь" (happy path) регистрации пользователя
@@ -112,3 +120,264 @@ func TestRegisterHandler_ValidationErrors(t *testing.T) {
 		})
 	}
 }
+
+// тест удаления юзера админом по id
+func TestDeleteUserHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		serviceErr error
+		callsSvc   bool
+		wantStatus int
+	}{
+		{name: "deleted", id: "5", callsSvc: true, wantStatus: http.StatusOK},
+		{name: "unknown id", id: "42", serviceErr: users.ErrUserNotFound, callsSvc: true, wantStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", wantStatus: http.StatusBadRequest},
+		{name: "service failure", id: "5", serviceErr: errors.New("db is down"), callsSvc: true, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, handler := setUpAuthHandlerTest(t)
+			if tt.callsSvc {
+				id, _ := strconv.Atoi(tt.id)
+				mockService.On("DeleteUser", mock.Anything, id).Return(tt.serviceErr)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: tt.id}}
+
+			handler.DeleteUserHandler(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// тест подтверждения email по ссылке из письма
+func TestVerifyEmailHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		serviceErr error
+		wantStatus int
+	}{
+		{name: "verified", token: "abc", wantStatus: http.StatusOK},
+		{name: "missing token", wantStatus: http.StatusBadRequest},
+		{name: "used token", token: "abc", serviceErr: ErrInvalidVerificationToken, wantStatus: http.StatusBadRequest},
+		{name: "expired token", token: "abc", serviceErr: ErrVerificationTokenExpired, wantStatus: http.StatusGone},
+		{name: "service failure", token: "abc", serviceErr: errors.New("db is down"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, handler := setUpAuthHandlerTest(t)
+			if tt.token != "" {
+				mockService.On("VerifyEmail", mock.Anything, tt.token).Return(tt.serviceErr)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/verify?token="+tt.token, nil)
+
+			handler.VerifyEmailHandler(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// тест запроса сброса пароля: ответ не зависит от результата сервиса
+func TestForgotPasswordHandler(t *testing.T) {
+	for _, serviceErr := range []error{nil, errors.New("smtp unavailable")} {
+		mockService, handler := setUpAuthHandlerTest(t)
+		mockService.On("ForgotPassword", mock.Anything, "test@example.com").Return(serviceErr)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("validatedData", &ForgotPasswordRequest{Email: "test@example.com"})
+
+		handler.ForgotPasswordHandler(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"message": "If the email is registered, a password reset link has been sent"}`, w.Body.String())
+		mockService.AssertExpectations(t)
+	}
+}
+
+// тест установки нового пароля по токену
+func TestResetPasswordHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+	}{
+		{name: "reset", wantStatus: http.StatusOK},
+		{name: "used token", serviceErr: ErrInvalidResetToken, wantStatus: http.StatusBadRequest},
+		{name: "expired token", serviceErr: ErrResetTokenExpired, wantStatus: http.StatusBadRequest},
+		{name: "service failure", serviceErr: errors.New("db is down"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, handler := setUpAuthHandlerTest(t)
+			mockService.On("ResetPassword", mock.Anything, "reset-token", "new-password").Return(tt.serviceErr)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("validatedData", &ResetPasswordRequest{Token: "reset-token", NewPassword: "new-password"})
+
+			handler.ResetPasswordHandler(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// тест смены пароля: надёжность нового пароля проверяется до обращения к сервису
+func TestChangePasswordHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		newPassword string
+		accessToken string
+		serviceErr  error
+		callsSvc    bool
+		wantStatus  int
+		wantBody    string
+	}{
+		{name: "changed", newPassword: "new-password!", accessToken: "access", callsSvc: true, wantStatus: http.StatusOK, wantBody: `"refresh_token":"access-refresh"`},
+		{name: "changed without session", newPassword: "new-password!", callsSvc: true, wantStatus: http.StatusOK, wantBody: "log in again"},
+		{name: "weak password", newPassword: "short", wantStatus: http.StatusBadRequest},
+		{name: "password without special chars", newPassword: "Password123", wantStatus: http.StatusBadRequest},
+		{name: "wrong current password", newPassword: "new-password!", serviceErr: ErrWrongPassword, callsSvc: true, wantStatus: http.StatusBadRequest},
+		{name: "too many attempts", newPassword: "new-password!", serviceErr: &LoginLockedError{RetryAfter: time.Minute}, callsSvc: true, wantStatus: http.StatusTooManyRequests},
+		{name: "service failure", newPassword: "new-password!", serviceErr: errors.New("db is down"), callsSvc: true, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, handler := setUpAuthHandlerTest(t)
+			if tt.callsSvc {
+				mockService.On("ChangePassword", mock.Anything, 7, "session-1", "old-password!", tt.newPassword, mock.Anything).Return(tt.accessToken, tt.accessToken+"-refresh", tt.serviceErr)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/password/change", nil)
+			c.Set("user_id", "7")
+			c.Set("session_id", "session-1")
+			c.Set("validatedData", &ChangePasswordRequest{CurrentPassword: "old-password!", NewPassword: tt.newPassword})
+
+			handler.ChangePasswordHandler(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// тест ответа на логин во время блокировки: 429 и Retry-After в секундах
+func TestLoginHandler_Locked(t *testing.T) {
+	mockService, handler := setUpAuthHandlerTest(t)
+	mockService.On("Login", mock.Anything, "test@example.com", "password123", mock.Anything).Return(&LoginLockedError{RetryAfter: 90500 * time.Millisecond})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+	c.Set("validatedData", &LoginRequest{Email: "test@example.com", Password: "password123"})
+
+	handler.LoginHandler(c)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "91", w.Header().Get("Retry-After"))
+	mockService.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+}
+
+// тест снятия блокировки логина админом
+func TestUnlockUserHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		serviceErr error
+		callsSvc   bool
+		wantStatus int
+	}{
+		{name: "unlocked", id: "5", callsSvc: true, wantStatus: http.StatusOK},
+		{name: "unknown id", id: "42", serviceErr: users.ErrUserNotFound, callsSvc: true, wantStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService, handler := setUpAuthHandlerTest(t)
+			if tt.callsSvc {
+				id, _ := strconv.Atoi(tt.id)
+				mockService.On("UnlockAccount", mock.Anything, id).Return(tt.serviceErr)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: tt.id}}
+
+			handler.UnlockUserHandler(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// тест logout: refresh токен проверяется, сессия удаляется только среди сессий пользователя из access токена
+func TestLogoutHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      func(tokens *jwt_stuff.JWT) string
+		serviceErr error
+		callsSvc   bool
+		wantStatus int
+	}{
+		{name: "own session", token: refreshTokenOfUser("7"), callsSvc: true, wantStatus: http.StatusOK},
+		{name: "session of another user", token: refreshTokenOfUser("8"), serviceErr: ErrInvalidRefreshToken, callsSvc: true, wantStatus: http.StatusUnauthorized},
+		{name: "forged token", token: func(*jwt_stuff.JWT) string {
+			return refreshTokenOfUser("7")(jwt_stuff.NewJWT("acc", "other", time.Minute, time.Hour))
+		}, wantStatus: http.StatusUnauthorized},
+		{name: "access token instead of refresh", token: func(tokens *jwt_stuff.JWT) string {
+			access, _, _ := tokens.GenerateTokensInFamily("test@example.com", "7", "user", true, 0, "session-1")
+			return access
+		}, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(moks.MockAuthService)
+			tokens := jwt_stuff.NewJWT("acc", "ref", time.Minute, time.Hour)
+			handler := NewAuthHandler(mockService, configs.LoadConfig(), tokens, logger.Nop())
+			if tt.callsSvc {
+				mockService.On("InvalidateRefreshToken", mock.Anything, 7, mock.Anything).Return(tt.serviceErr)
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(`{"refresh_token":"`+tt.token(tokens)+`"}`))
+			c.Set("user_id", "7")
+
+			handler.LogoutHandler(c)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// refresh токен пользователя userId, подписанный tokens
+func refreshTokenOfUser(userId string) func(tokens *jwt_stuff.JWT) string {
+	return func(tokens *jwt_stuff.JWT) string {
+		_, refresh, _ := tokens.GenerateTokensInFamily("test@example.com", userId, "user", true, 0, "session-1")
+		return refresh
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"simple_gin_server/configs"
+	"simple_gin_server/pkg/db"
+	"strings"
+	"time"
+)
+
+// Ошибка логина во время блокировки после серии неудачных попыток, RetryAfter - оставшееся время блокировки
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", ErrTooManyLoginAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginLockedError) Is(target error) bool {
+	return target == ErrTooManyLoginAttempts
+}
+
+// история блокировок хранится сутки: в течение суток каждая следующая блокировка вдвое дольше предыдущей
+const lockoutHistoryTTL = time.Hour * 24
+
+// Счётчики неудачных попыток логина в Redis, отдельно по email и по IP. Время жизни счётчика продлевается
+// при каждой неудаче (скользящее окно): после LoginAttemptWindow без ошибок попытки обнуляются.
+// При недоступности Redis логин не блокируется
+type loginAttempts struct {
+	redisRepo db.ReddisRepoInterface
+	config    configs.AuthConfig
+	log       *slog.Logger
+}
+
+// объект подсчёта попыток: email или IP со своим лимитом
+type attemptSubject struct {
+	key   string
+	limit int
+}
+
+func newLoginAttempts(redisRepo db.ReddisRepoInterface, config configs.AuthConfig, log *slog.Logger) *loginAttempts {
+	return &loginAttempts{redisRepo: redisRepo, config: config, log: log}
+}
+
+// Проверка блокировки email и IP перед проверкой пароля
+func (l *loginAttempts) check(ctx context.Context, email, ip string) error {
+	for _, subject := range l.subjects(email, ip) {
+		ttl, err := l.redisRepo.TTL(ctx, lockKey(subject.key))
+		if err != nil {
+			if !errors.Is(err, db.ErrKeyNotFound) {
+				l.log.ErrorContext(ctx, "login lock check failed, login allowed", "error", err)
+			}
+			continue
+		}
+		if ttl > 0 {
+			return &LoginLockedError{RetryAfter: ttl}
+		}
+	}
+	return nil
+}
+
+// Учёт неудачной попытки. Если лимит попыток исчерпан, email (или IP) блокируется и возвращается LoginLockedError
+func (l *loginAttempts) fail(ctx context.Context, email, ip string) error {
+	var locked error
+	for _, subject := range l.subjects(email, ip) {
+		key := failKey(subject.key)
+		count, err := l.redisRepo.Incr(ctx, key)
+		if err != nil {
+			l.log.ErrorContext(ctx, "failed to count login attempt", "error", err)
+			continue
+		}
+		if err := l.redisRepo.Expire(ctx, key, l.config.LoginAttemptWindow); err != nil {
+			l.log.ErrorContext(ctx, "failed to extend login attempt window", "error", err)
+		}
+
+		if count >= int64(subject.limit) {
+			if duration, ok := l.lock(ctx, subject.key); ok && locked == nil {
+				locked = &LoginLockedError{RetryAfter: duration}
+			}
+		}
+	}
+	return locked
+}
+
+// Сброс счётчиков email после успешного логина (счётчик IP не сбрасывается: с одного IP могут перебирать разные email)
+func (l *loginAttempts) reset(ctx context.Context, email string) {
+	subject := emailSubject(email)
+	if err := l.redisRepo.Del(ctx, failKey(subject), lockoutsKey(subject)); err != nil {
+		l.log.ErrorContext(ctx, "failed to reset login attempts", "error", err)
+	}
+}
+
+// Снятие блокировки email (администратором)
+func (l *loginAttempts) unlock(ctx context.Context, email string) error {
+	subject := emailSubject(email)
+	if err := l.redisRepo.Del(ctx, lockKey(subject), failKey(subject), lockoutsKey(subject)); err != nil {
+		return fmt.Errorf("redis del failed: %w", err)
+	}
+	return nil
+}
+
+// блокирует объект подсчёта попыток, длительность удваивается с каждой блокировкой в течение суток
+func (l *loginAttempts) lock(ctx context.Context, subject string) (time.Duration, bool) {
+	lockouts, err := l.redisRepo.Incr(ctx, lockoutsKey(subject))
+	if err != nil {
+		l.log.ErrorContext(ctx, "failed to count lockouts", "error", err)
+		lockouts = 1
+	}
+	if err := l.redisRepo.Expire(ctx, lockoutsKey(subject), lockoutHistoryTTL); err != nil {
+		l.log.ErrorContext(ctx, "failed to extend lockout history", "error", err)
+	}
+
+	duration := lockoutDuration(l.config.LoginLockout, l.config.LoginMaxLockout, lockouts)
+	if err := l.redisRepo.Set(ctx, lockKey(subject), "locked", duration); err != nil {
+		l.log.ErrorContext(ctx, "failed to lock login", "error", err)
+		return 0, false
+	}
+
+	// после блокировки попытки считаются заново
+	if err := l.redisRepo.Del(ctx, failKey(subject)); err != nil {
+		l.log.ErrorContext(ctx, "failed to reset login attempts after lock", "error", err)
+	}
+	return duration, true
+}
+
+func (l *loginAttempts) subjects(email, ip string) []attemptSubject {
+	subjects := []attemptSubject{{key: emailSubject(email), limit: l.config.LoginMaxAttempts}}
+	if ip != "" {
+		subjects = append(subjects, attemptSubject{key: "ip:" + ip, limit: l.config.LoginMaxAttemptsPerIP})
+	}
+	return subjects
+}
+
+// длительность блокировки номер lockouts: base, 2*base, 4*base... но не больше max
+func lockoutDuration(base, max time.Duration, lockouts int64) time.Duration {
+	duration := base
+	for i := int64(1); i < lockouts && duration < max; i++ {
+		duration *= 2
+	}
+	if duration > max {
+		return max
+	}
+	return duration
+}
+
+// email без учёта регистра, чтобы блокировку нельзя было обойти, меняя регистр букв
+func emailSubject(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+// ключ Redis счётчика неудачных попыток
+func failKey(subject string) string {
+	return fmt.Sprintf("login_fail:%s", subject)
+}
+
+// ключ Redis блокировки логина
+func lockKey(subject string) string {
+	return fmt.Sprintf("login_lock:%s", subject)
+}
+
+// ключ Redis числа блокировок за сутки
+func lockoutsKey(subject string) string {
+	return fmt.Sprintf("login_lockouts:%s", subject)
+}
@@ -5,8 +5,9 @@ type HealthResponse struct {
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,min=6"`
+	DeviceName string `json:"device_name" validate:"max=100"` // название устройства для списка сессий, необязательно
 }
 
 type RegisterRequest struct {
@@ -23,6 +24,20 @@ type RegisterResponce struct {
 	Token string `json:"token"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"` // требования к надёжности проверяются в хэндлере
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"` // требования к надёжности проверяются в хэндлере
+}
+
 // Структура для входящего запроса
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
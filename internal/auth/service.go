@@ -2,44 +2,108 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
+	"simple_gin_server/configs"
+	"simple_gin_server/internal/sessions"
 	"simple_gin_server/internal/users"
+	"simple_gin_server/pkg/cache"
 	"simple_gin_server/pkg/db"
 	"simple_gin_server/pkg/jwt_stuff"
-
+	"simple_gin_server/pkg/logger"
+	"simple_gin_server/pkg/mailer"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // Интерфейс для слоя authService для использования другими источниками
 type ServiceInterface interface {
 	Register(ctx context.Context, email, password string) error
-	Login(ctx context.Context, email, password string) error
+	Login(ctx context.Context, email, password, ip string) error
 	GetUserList(ctx context.Context) ([]string, error)
-	AddRefreshTokenToDb(ctx context.Context, email, refreshToken string) error
-	InvalidateRefreshToken(ctx context.Context, refreshToken string) error
+	CreateSession(ctx context.Context, userId int, refreshToken string, client sessions.ClientInfo) error
+	InvalidateRefreshToken(ctx context.Context, userId int, claims jwt_stuff.CustomClaims) error
 	ExistsInBlackList(ctx context.Context, key string) (bool, error)
 	GetUserByClaims(ctx context.Context, claims jwt_stuff.CustomClaims) (*users.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*users.User, error)
+	DeleteUser(ctx context.Context, id int) error
+	ValidateRefreshToken(ctx context.Context, claims jwt_stuff.CustomClaims) (*users.User, error)
+	RotateRefreshToken(ctx context.Context, claims jwt_stuff.CustomClaims, newToken string) error
+	ListSessions(ctx context.Context, userId int) ([]sessions.Session, error)
+	RevokeSession(ctx context.Context, userId int, sessionId string) error
+	LogoutAll(ctx context.Context, userId int) error
+	TokenState(ctx context.Context, userId int) (int, bool, error)
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, userId int, sessionId, currentPassword, newPassword, ip string) (string, string, error)
+	UnlockAccount(ctx context.Context, userId int) error
 }
 
 type AuthService struct {
-	repo      users.UserRepoInterface
-	redisRepo db.ReddisRepoInterface
+	repo        users.UserRepoInterface
+	sessionRepo sessions.SessionRepoInterface
+	redisRepo   db.ReddisRepoInterface
+	tokens      *jwt_stuff.JWT
+	mailer      mailer.Mailer
+	config      *configs.Config
+	log         *slog.Logger
+
+	// in-process кэш перед Redis для проверок, выполняемых на каждый запрос
+	tokenStates   *cache.Cache[tokenState]
+	revokedTokens *cache.Cache[bool]
+
+	// счётчики неудачных попыток логина (защита от перебора паролей)
+	loginAttempts *loginAttempts
+
+	// фоновые задачи, продолжающиеся после ответа на запрос (письма сброса пароля)
+	background sync.WaitGroup
+}
+
+// версия токенов и признак активности пользователя
+type tokenState struct {
+	version  int
+	isActive bool
 }
 
+// максимальное число записей каждого in-process кэша
+const localCacheSize = 10_000
+
+// время на фоновую задачу (поиск пользователя, запись токена и отправка письма сброса пароля)
+const backgroundTaskTimeout = time.Second * 30
+
 // Конструктор слоя сервис
-func NewAuthService(repo users.UserRepoInterface, redisRepo db.ReddisRepoInterface) *AuthService {
+func NewAuthService(repo users.UserRepoInterface, sessionRepo sessions.SessionRepoInterface, redisRepo db.ReddisRepoInterface, tokens *jwt_stuff.JWT, mailer mailer.Mailer, config *configs.Config, log *slog.Logger) *AuthService {
 	return &AuthService{
-		repo:      repo,
-		redisRepo: redisRepo,
+		repo:          repo,
+		sessionRepo:   sessionRepo,
+		redisRepo:     redisRepo,
+		tokens:        tokens,
+		mailer:        mailer,
+		config:        config,
+		log:           log,
+		tokenStates:   cache.New[tokenState](config.Auth.LocalCacheTTL, localCacheSize),
+		revokedTokens: cache.New[bool](config.Auth.LocalCacheTTL, localCacheSize),
+		loginAttempts: newLoginAttempts(redisRepo, config.Auth, log),
 	}
 }
 
-// Добавление нового пользователя в базу с хэшированным паролем
+// Добавление нового пользователя в базу с хэшированным паролем. Пользователь неактивен,
+// пока не подтвердит email по ссылке из отправленного письма
 func (s *AuthService) Register(ctx context.Context, email, password string) error {
 	// Проверяем не отменен ли контекст
 	if err := ctx.Err(); err != nil {
@@ -61,100 +125,425 @@ func (s *AuthService) Register(ctx context.Context, email, password string) erro
 		return errors.New("ошибка при хешировании пароля")
 	}
 
-	err = s.repo.AddUser(ctx, email, string(hashedPassword), "user", true)
+	userId, err := s.repo.AddUser(ctx, email, string(hashedPassword), "user", false)
 	if err != nil {
 		return errors.New("failed to add new user to the DB")
 	}
+
+	if err := s.sendVerificationEmail(ctx, email, userId); err != nil {
+		// без письма учётную запись не подтвердить, а повторная регистрация упёрлась бы в занятый email:
+		// удаляем пользователя, чтобы регистрацию можно было повторить
+		if _, delErr := s.repo.DeleteUserById(context.WithoutCancel(ctx), userId); delErr != nil {
+			s.log.ErrorContext(ctx, "failed to roll back registration", "user_id", userId, "error", delErr)
+		}
+		return err
+	}
 	return nil
 }
 
-// Логи юзера по email и pasword, при успешном логировании - в ответе будет access и refresh jwt токены
-func (s *AuthService) Login(ctx context.Context, email, password string) error {
+// Логи юзера по email и pasword, при успешном логировании - в ответе будет access и refresh jwt токены.
+// После серии неудачных попыток с одним email или с одного ip логин временно блокируется (LoginLockedError)
+func (s *AuthService) Login(ctx context.Context, email, password, ip string) error {
 	// Проверяем не отменен ли контекст
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
+	// во время блокировки пароль не проверяется
+	if err := s.loginAttempts.check(ctx, email, ip); err != nil {
+		return err
+	}
+
 	// Проверяем существует ли пользователь с данным email уже в базе
 	existedUser, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
-		return err
+		return s.loginFailed(ctx, email, ip, err)
 	}
 	if existedUser == nil {
-		log.Printf("error during search in the DB: %v", existedUser)
-		return errors.New(users.ErrWrongCredentials)
+		s.log.InfoContext(ctx, "login with unknown email")
+		return s.loginFailed(ctx, email, ip, errors.New(users.ErrWrongCredentials))
 	}
 
 	//сравниваем хэши паролей, тот, что в базе и тот, что логинится
 	err = bcrypt.CompareHashAndPassword([]byte(existedUser.HashPass), []byte(password))
 	if err != nil {
-		return errors.New(users.ErrWrongCredentials)
+		return s.loginFailed(ctx, email, ip, errors.New(users.ErrWrongCredentials))
+	}
+	s.loginAttempts.reset(ctx, email)
+
+	// состояние учётной записи сообщаем только после проверки пароля
+	if !existedUser.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	if !existedUser.IsActive {
+		return ErrAccountDeactivated
 	}
 	return nil
 }
 
-// Получаем список email зарегестрированных пользователей
-func (s *AuthService) GetUserList(ctx context.Context) ([]string, error) {
+// учитывает неудачную попытку логина: если она исчерпала лимит - возвращается ошибка блокировки, иначе err
+func (s *AuthService) loginFailed(ctx context.Context, email, ip string, err error) error {
+	if locked := s.loginAttempts.fail(ctx, email, ip); locked != nil {
+		return locked
+	}
+	return err
+}
+
+// Снятие блокировки логина пользователя после неудачных попыток (только для админа)
+func (s *AuthService) UnlockAccount(ctx context.Context, userId int) error {
 	// Проверяем не отменен ли контекст
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return err
 	}
 
-	res, err := s.repo.GetEmailLIst(ctx)
+	user, err := s.repo.FindById(ctx, userId)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return res, nil
+
+	return s.loginAttempts.unlock(ctx, user.Email)
 }
 
-// Добавляем refresh токен в БД
-func (s *AuthService) AddRefreshTokenToDb(ctx context.Context, email, refreshToken string) error {
+// Подтверждение email по токену из ссылки: токен одноразовый (jti удаляется из Redis при первом использовании),
+// пользователь становится активным
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
 	// Проверяем не отменен ли контекст
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	err := s.repo.AddRefreshToken(ctx, email, refreshToken)
+	claims, err := s.tokens.VerificationVerifier().Verify(ctx, token)
+	if err != nil {
+		if errors.Is(err, jwt_stuff.ErrTokenExpired) {
+			return ErrVerificationTokenExpired
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidVerificationToken, err)
+	}
+
+	userIdStr, err := s.redisRepo.GetDel(ctx, verifyTokenKey(claims.ID))
 	if err != nil {
+		if errors.Is(err, db.ErrKeyNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return fmt.Errorf("redis getdel failed: %w", err)
+	}
+	if userIdStr != claims.UserId {
+		return ErrInvalidVerificationToken
+	}
+
+	userId, err := strconv.Atoi(userIdStr)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	if err := s.repo.VerifyEmail(ctx, userId); err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return ErrInvalidVerificationToken
+		}
 		return err
 	}
+
+	// активность пользователя изменилась - сбрасываем кэш состояния токенов
+	if err := s.invalidateTokenState(ctx, userId); err != nil {
+		s.log.ErrorContext(ctx, "failed to invalidate token state", logger.KeyUserID, userId, "error", err)
+	}
 	return nil
 }
 
-// Инвалидируем refresh токен в БД
-// ---------------------------------------????????????????????????????????????????????????
-func (s *AuthService) InvalidateRefreshToken(ctx context.Context, refreshToken string) error {
+// Запрос сброса пароля: пользователю с таким email отправляется письмо с одноразовым токеном.
+// Поиск пользователя, запись токена и отправка письма выполняются в фоне: ни ответ, ни время ответа
+// не зависят от того, зарегистрирован ли email (нельзя перебором узнать email пользователей)
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
 	// Проверяем не отменен ли контекст
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	// Получаем jti из токена
+	taskCtx, cancel := context.WithTimeout(logger.Detach(ctx), backgroundTaskTimeout)
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		defer cancel()
+		if err := s.sendPasswordReset(taskCtx, email); err != nil {
+			s.log.ErrorContext(taskCtx, "password reset request failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Ожидание завершения фоновых задач сервиса (при остановке, до закрытия хранилищ)
+func (s *AuthService) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// письмо со ссылкой сброса пароля; для неизвестного email письмо не отправляется и ошибка не возвращается
+func (s *AuthService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		s.log.InfoContext(ctx, "password reset user lookup failed, no email sent", "error", err)
+		return nil
+	}
+
+	token, err := newResetToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+
+	// в БД хранится только хэш: утечка таблицы не даёт возможности сбросить пароль
+	exp := s.config.Auth.ResetTokenExp
+	if err := s.repo.CreatePasswordReset(ctx, user.Id, hashResetToken(token), time.Now().Add(exp)); err != nil {
+		return err
+	}
+
+	link, err := resetLink(s.config.Mail.ResetURL, token)
+	if err != nil {
+		return err
+	}
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body:    fmt.Sprintf("To set a new password follow the link (valid for %v):\n%s\n\nIf you did not request a password reset, ignore this email.", exp, link),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+	return nil
+}
+
+// Сброс пароля по одноразовому токену из письма: пароль хэшируется заново, все токены
+// пользователя отзываются (как при logout со всех устройств)
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// токен удаляется при первой попытке использования, в том числе если он истёк
+	userId, expiresAt, err := s.repo.ConsumePasswordReset(ctx, hashResetToken(token))
+	if err != nil {
+		if errors.Is(err, users.ErrResetTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	if time.Now().After(expiresAt) {
+		return ErrResetTokenExpired
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("ошибка при хешировании пароля")
+	}
+
+	if err := s.repo.UpdatePassword(ctx, userId, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	// пароль мог быть скомпрометирован - сессии, открытые со старым паролем, закрываются.
+	// Новый пароль уже сохранён и токен сброса израсходован, поэтому ошибка только логируется:
+	// ответ 500 заставил бы пользователя повторять сброс, который на самом деле прошёл
+	if err := s.LogoutAll(ctx, userId); err != nil {
+		s.log.ErrorContext(ctx, "failed to revoke sessions after password reset", "user_id", userId, "error", err)
+	}
+	return nil
+}
+
+// Смена пароля авторизованным пользователем после проверки текущего пароля. Неверный текущий пароль
+// учитывается как неудачная попытка логина (с блокировкой): иначе смена пароля - способ перебирать пароль
+// с украденным access токеном. Пароль мог быть скомпрометирован, поэтому все выданные токены отзываются
+// (как при LogoutAll), сессии на других устройствах закрываются, а сессии sessionId, из которой меняется
+// пароль, выдаётся новая пара токенов. Без сессии (токены, выданные до появления id сессии в access токене)
+// закрываются все сессии и токены не выдаются - нужен повторный логин
+func (s *AuthService) ChangePassword(ctx context.Context, userId int, sessionId, currentPassword, newPassword, ip string) (string, string, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+
+	user, err := s.repo.FindById(ctx, userId)
+	if err != nil {
+		return "", "", err
+	}
+
+	// во время блокировки пароль не проверяется
+	if err := s.loginAttempts.check(ctx, user.Email, ip); err != nil {
+		return "", "", err
+	}
+
+	//сравниваем хэш пароля в базе с текущим паролем из запроса
+	if err := bcrypt.CompareHashAndPassword([]byte(user.HashPass), []byte(currentPassword)); err != nil {
+		return "", "", s.loginFailed(ctx, user.Email, ip, ErrWrongPassword)
+	}
+	s.loginAttempts.reset(ctx, user.Email)
+
+	if currentPassword == newPassword {
+		return "", "", ErrSamePassword
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", errors.New("ошибка при хешировании пароля")
+	}
+
+	if err := s.repo.UpdatePassword(ctx, userId, string(hashedPassword)); err != nil {
+		return "", "", err
+	}
+
+	// access и refresh токены старой версии перестают приниматься на всех устройствах
+	version, err := s.repo.IncrementTokenVersion(ctx, userId)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.invalidateTokenState(ctx, userId); err != nil {
+		return "", "", err
+	}
+
+	if sessionId == "" {
+		return "", "", s.sessionRepo.DeleteUserSessions(ctx, userId)
+	}
+	if err := s.sessionRepo.DeleteOtherUserSessions(ctx, userId, sessionId); err != nil {
+		return "", "", err
+	}
+	return s.reissueSessionTokens(ctx, user, version, sessionId)
+}
+
+// новая пара токенов версии version в сессии sessionId: в сессии сохраняется jti нового refresh токена.
+// Если сессию успели закрыть, токены не выдаются (пустые строки)
+func (s *AuthService) reissueSessionTokens(ctx context.Context, user *users.User, version int, sessionId string) (string, string, error) {
+	session, err := s.sessionRepo.GetSessionById(ctx, sessionId)
+	if errors.Is(err, sessions.ErrSessionNotFound) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if session.UserID != user.Id {
+		return "", "", nil
+	}
+
+	accessToken, refreshToken, err := s.tokens.GenerateTokensInFamily(user.Email, strconv.Itoa(user.Id), user.Role, user.IsActive, version, sessionId)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate tokens: %w", err)
+	}
 	claims, err := jwt_stuff.ParseTokenWithoutVerification(refreshToken)
-	log.Printf("[service.go]---[InvalidateRefreshToken()]---[ParseTokenWithoutVerification()]---err: %v", err)
 	if err != nil {
+		return "", "", err
+	}
+
+	replaced, err := s.sessionRepo.RotateSession(ctx, sessionId, session.Jti, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return "", "", err
+	}
+	if !replaced {
+		// сессию закрыли или обновили её токен параллельным запросом
+		return "", "", nil
+	}
+	return accessToken, refreshToken, nil
+}
+
+// выдаёт одноразовый токен подтверждения email (jti хранится в Redis до истечения токена) и отправляет ссылку письмом
+func (s *AuthService) sendVerificationEmail(ctx context.Context, email string, userId int) error {
+	exp := s.config.Auth.VerifyTokenExp
+	token, jti, err := s.tokens.GenerateVerificationToken(email, strconv.Itoa(userId), exp)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	if err := s.redisRepo.Set(ctx, verifyTokenKey(jti), strconv.Itoa(userId), exp); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/verify?token=%s", strings.TrimRight(s.config.Mail.BaseURL, "/"), url.QueryEscape(token))
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email",
+		Body:    fmt.Sprintf("To confirm your email follow the link (valid for %v):\n%s", exp, link),
+	})
+	if err != nil {
+		// токен неотправленного письма больше не нужен
+		if delErr := s.redisRepo.Del(context.WithoutCancel(ctx), verifyTokenKey(jti)); delErr != nil {
+			s.log.ErrorContext(ctx, "failed to delete verification token", "error", delErr)
+		}
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+// Получаем список email зарегестрированных пользователей
+func (s *AuthService) GetUserList(ctx context.Context) ([]string, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res, err := s.repo.GetEmailLIst(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Создание сессии нового логина: сессия получает id семейства refresh токена и хранит jti его текущего токена
+func (s *AuthService) CreateSession(ctx context.Context, userId int, refreshToken string, client sessions.ClientInfo) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	// Дополнительная проверка что это именно refresh token
-	if claims.TokenType != "refresh" {
+	claims, err := jwt_stuff.ParseTokenWithoutVerification(refreshToken)
+	if err != nil {
+		return err
+	}
+	if claims.TokenType != "refresh" || claims.FamilyId == "" {
 		return errors.New("not a refresh token")
 	}
 
-	// Вычисляем оставшееся время жизни токена
-	ttl := time.Until(claims.ExpiresAt.Time) // Верный способ для jwt.NumericDate
-	log.Printf("[service.go]---[InvalidateRefreshToken()]---TTL(refresh token): %v", ttl)
+	return s.sessionRepo.CreateSession(ctx, &sessions.Session{
+		ID:         claims.FamilyId,
+		UserID:     userId,
+		Jti:        claims.ID,
+		DeviceName: client.DeviceName,
+		UserAgent:  truncateRunes(client.UserAgent, sessions.MaxUserAgentLen), // заголовок задаёт клиент, длина не ограничена
+		IP:         client.IP,
+		ExpiresAt:  claims.ExpiresAt.Time,
+	})
+}
 
-	// Сохраняем в Redis
-	key := fmt.Sprintf("refresh_token:%s", claims.ID)
-	if err := s.redisRepo.Set(ctx, key, "invalid", ttl); err != nil {
-		return fmt.Errorf("redis set failed: %w", err)
+// обрезает строку до n символов (не байт, длина VARCHAR в Postgres считается в символах)
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
 	}
+	return string([]rune(s)[:n])
+}
 
-	// 2. Очистка в PostgreSQL
-	if err := s.repo.ClearRefreshToken(ctx, claims.Email); err != nil {
-		// Важно: даже если очистка в БД не удалась, токен уже инвалидирован в Redis
-		log.Printf("warning: failed to clear refresh token in DB for user %s: %v", claims.Email, err)
+// Инвалидируем refresh токен: удаляем сессию, текущим токеном которой он является (logout).
+// claims - проверенного refresh токена; токен чужого пользователя не принимается,
+// а сессия удаляется только среди сессий userId
+func (s *AuthService) InvalidateRefreshToken(ctx context.Context, userId int, claims jwt_stuff.CustomClaims) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if claims.UserId != strconv.Itoa(userId) {
+		return ErrInvalidRefreshToken
+	}
+
+	// сессия уже удалена (повторный logout, отзыв с другого устройства) - считаем logout выполненным
+	if err := s.sessionRepo.DeleteUserSessionByJti(ctx, userId, claims.ID); err != nil && !errors.Is(err, sessions.ErrSessionNotFound) {
+		return err
 	}
 	return nil
 }
@@ -196,3 +585,312 @@ func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*users.
 
 	return existedUser, nil
 }
+
+// Удаление пользователя по id (только для админа) вместе с профилем, совпадениями и действиями.
+// Сессии пользователя удаляются каскадно, поэтому его refresh токены больше не пройдут проверку
+func (s *AuthService) DeleteUser(ctx context.Context, id int) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.DeleteUserById(ctx, id); err != nil {
+		return err
+	}
+
+	// access токены удалённого пользователя перестают приниматься после сброса кэша состояния
+	if err := s.invalidateTokenState(ctx, id); err != nil {
+		s.log.ErrorContext(ctx, "failed to invalidate token state of deleted user", logger.KeyUserID, id, "error", err)
+	}
+	return nil
+}
+
+// Проверка refresh токена перед ротацией: сессия токена существует и её текущий токен - именно этот.
+// Повторное предъявление уже заменённого токена означает, что токен украден: сессия отзывается
+func (s *AuthService) ValidateRefreshToken(ctx context.Context, claims jwt_stuff.CustomClaims) (*users.User, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// токены, выданные до появления сессий, не принимаются
+	if claims.FamilyId == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, err := s.sessionRepo.GetSessionById(ctx, claims.FamilyId)
+	if err != nil {
+		if errors.Is(err, sessions.ErrSessionNotFound) {
+			return nil, ErrRefreshTokenRevoked
+		}
+		return nil, err
+	}
+	if strconv.Itoa(session.UserID) != claims.UserId {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if session.Jti != claims.ID {
+		if err := s.sessionRepo.DeleteSession(ctx, session.ID); err != nil {
+			s.log.ErrorContext(ctx, "failed to revoke session after refresh token reuse", "session_id", session.ID, "error", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	// роль и активность берутся из БД (могли измениться после логина)
+	user, err := s.repo.FindByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRefreshToken, err)
+	}
+	if user.Id != session.UserID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// после logout со всех устройств токены старой версии не принимаются
+	if claims.Version != user.TokenVersion {
+		return nil, ErrRefreshTokenRevoked
+	}
+
+	return user, nil
+}
+
+// Ротация refresh токена: в сессии атомарно сохраняется jti newToken вместо jti предъявленного токена.
+// Если предъявленный токен уже был заменён параллельным запросом, сессия отзывается
+func (s *AuthService) RotateRefreshToken(ctx context.Context, claims jwt_stuff.CustomClaims, newToken string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	newClaims, err := jwt_stuff.ParseTokenWithoutVerification(newToken)
+	if err != nil {
+		return err
+	}
+
+	replaced, err := s.sessionRepo.RotateSession(ctx, claims.FamilyId, claims.ID, newClaims.ID, newClaims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+	if !replaced {
+		if err := s.sessionRepo.DeleteSession(ctx, claims.FamilyId); err != nil {
+			s.log.ErrorContext(ctx, "failed to revoke session after refresh token reuse", "session_id", claims.FamilyId, "error", err)
+		}
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+// Получение действующих сессий пользователя
+func (s *AuthService) ListSessions(ctx context.Context, userId int) ([]sessions.Session, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.sessionRepo.GetUserSessions(ctx, userId)
+}
+
+// Отзыв сессии пользователя (logout на другом устройстве)
+func (s *AuthService) RevokeSession(ctx context.Context, userId int, sessionId string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := uuid.Parse(sessionId); err != nil {
+		return sessions.ErrSessionNotFound
+	}
+
+	return s.sessionRepo.DeleteUserSession(ctx, userId, sessionId)
+}
+
+// Logout со всех устройств: версия токенов пользователя увеличивается (перестают приниматься все
+// выданные access и refresh токены), кэш состояния токенов сбрасывается, сессии удаляются
+func (s *AuthService) LogoutAll(ctx context.Context, userId int) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.IncrementTokenVersion(ctx, userId); err != nil {
+		return err
+	}
+
+	// без сброса кэша access токены старой версии принимались бы до истечения ключа
+	if err := s.invalidateTokenState(ctx, userId); err != nil {
+		return err
+	}
+
+	return s.sessionRepo.DeleteUserSessions(ctx, userId)
+}
+
+// Версия токенов и признак активности пользователя: in-process кэш, затем Redis, затем БД.
+// Значение из БД записывается в Redis не дольше времени жизни access токена и только если ключ
+// не изменился с момента чтения: иначе параллельный LogoutAll (или деактивация) мог увеличить версию
+// между чтением из БД и записью, и старая версия вернулась бы в кэш всех экземпляров
+func (s *AuthService) TokenState(ctx context.Context, userId int) (int, bool, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+
+	key := tokenStateKey(userId)
+	if state, ok := s.tokenStates.Get(key); ok {
+		return state.version, state.isActive, nil
+	}
+
+	// значение ключа до чтения из БД: "" (ключа нет) или метка сброса кэша
+	observed := ""
+	cached, err := s.redisRepo.Get(ctx, key)
+	switch {
+	case err == nil:
+		if state, ok := parseTokenState(cached); ok {
+			s.tokenStates.Set(key, state)
+			return state.version, state.isActive, nil
+		}
+		observed = cached
+	case !errors.Is(err, db.ErrKeyNotFound):
+		// Redis недоступен - проверяем по БД, кэши не трогаем
+		s.log.ErrorContext(ctx, "token state cache unavailable, reading from DB", "error", err)
+		return s.repo.GetTokenState(ctx, userId)
+	}
+
+	version, isActive, err := s.repo.GetTokenState(ctx, userId)
+	if err != nil {
+		return 0, false, err
+	}
+
+	state := tokenState{version: version, isActive: isActive}
+	stored, err := s.redisRepo.CompareAndSet(ctx, key, observed, formatTokenState(state), s.config.Auth.AccessTokenExp)
+	switch {
+	case err != nil:
+		s.log.ErrorContext(ctx, "failed to cache token state", "error", err)
+	case !stored:
+		// кэш сброшен после чтения из БД: прочитанное состояние могло устареть, не кэшируем его
+		return version, isActive, nil
+	}
+	s.tokenStates.Set(key, state)
+	return version, isActive, nil
+}
+
+// Заносит access токен в черный список Redis на оставшееся время его жизни (logout)
+func (s *AuthService) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// токен уже истёк, в черный список заносить нечего
+		return nil
+	}
+
+	key := accessTokenKey(jti)
+	if err := s.redisRepo.Set(ctx, key, "revoked", ttl); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	s.revokedTokens.Set(key, true)
+	return nil
+}
+
+// Проверка access токена по черному списку: in-process кэш, затем Redis
+func (s *AuthService) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	key := accessTokenKey(jti)
+	if revoked, ok := s.revokedTokens.Get(key); ok {
+		return revoked, nil
+	}
+
+	revoked, err := s.redisRepo.Exists(ctx, key)
+	if err != nil {
+		return false, err
+	}
+
+	s.revokedTokens.Set(key, revoked)
+	return revoked, nil
+}
+
+// сбрасывает кэши состояния токенов пользователя (после изменения версии или активности).
+// Вместо удаления ключа в Redis записывается уникальная метка сброса: TokenState, прочитавший
+// БД до сброса, видит, что ключ изменился, и не записывает устаревшее состояние
+func (s *AuthService) invalidateTokenState(ctx context.Context, userId int) error {
+	key := tokenStateKey(userId)
+	s.tokenStates.Delete(key)
+
+	if err := s.redisRepo.Set(ctx, key, tokenStateInvalidated+uuid.NewString(), s.config.Auth.AccessTokenExp); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+// префикс метки сброса кэша состояния токенов (не разбирается parseTokenState)
+const tokenStateInvalidated = "invalidated:"
+
+// ключ Redis с состоянием токенов пользователя
+func tokenStateKey(userId int) string {
+	return fmt.Sprintf("token_state:%d", userId)
+}
+
+// случайный токен сброса пароля (256 бит)
+func newResetToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// ссылка на страницу сброса пароля с токеном в параметре token (параметры самой страницы сохраняются)
+func resetLink(page, token string) (string, error) {
+	u, err := url.Parse(page)
+	if err != nil {
+		return "", fmt.Errorf("invalid password reset page url: %w", err)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// хэш токена сброса пароля для хранения в БД
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ключ Redis неиспользованного токена подтверждения email
+func verifyTokenKey(jti string) string {
+	return fmt.Sprintf("email_verify:%s", jti)
+}
+
+// ключ Redis отозванного access токена
+func accessTokenKey(jti string) string {
+	return fmt.Sprintf("access_token:%s", jti)
+}
+
+// состояние токенов хранится в Redis строкой "<версия>:<активен>", например "3:true"
+func formatTokenState(state tokenState) string {
+	return fmt.Sprintf("%d:%t", state.version, state.isActive)
+}
+
+func parseTokenState(value string) (tokenState, bool) {
+	versionStr, activeStr, found := strings.Cut(value, ":")
+	if !found {
+		return tokenState{}, false
+	}
+
+	version, err := strconv.Atoi(versionStr)
+	if err != nil {
+		return tokenState{}, false
+	}
+	isActive, err := strconv.ParseBool(activeStr)
+	if err != nil {
+		return tokenState{}, false
+	}
+
+	return tokenState{version: version, isActive: isActive}, true
+}
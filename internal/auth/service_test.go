@@ -2,34 +2,131 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"simple_gin_server/configs"
 	"simple_gin_server/internal/moks"
+	"simple_gin_server/internal/sessions"
 	"simple_gin_server/internal/users"
+	"simple_gin_server/pkg/db"
+	"simple_gin_server/pkg/jwt_stuff"
+	"simple_gin_server/pkg/logger"
+	"simple_gin_server/pkg/mailer"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // функция настройки тестового окружения
 func setUpServiceTest(t *testing.T) (*moks.MockUserRepo, *moks.MockRedisRepo, *AuthService) {
+	mockUserRepo, _, mockReddisRepo, service := setUpServiceMocks(t)
+	return mockUserRepo, mockReddisRepo, service
+}
+
+// функция настройки тестового окружения для тестов сессий
+func setUpSessionServiceTest(t *testing.T) (*moks.MockUserRepo, *moks.MockSessionRepo, *AuthService) {
+	mockUserRepo, mockSessionRepo, _, service := setUpServiceMocks(t)
+	return mockUserRepo, mockSessionRepo, service
+}
+
+func setUpServiceMocks(t *testing.T) (*moks.MockUserRepo, *moks.MockSessionRepo, *moks.MockRedisRepo, *AuthService) {
+	mockUserRepo, mockSessionRepo, mockReddisRepo, _, service := setUpAllServiceMocks(t)
+	return mockUserRepo, mockSessionRepo, mockReddisRepo, service
+}
+
+// функция настройки тестового окружения для тестов с отправкой писем
+func setUpMailServiceTest(t *testing.T) (*moks.MockUserRepo, *moks.MockRedisRepo, *moks.MockMailer, *AuthService) {
+	mockUserRepo, _, mockReddisRepo, mockMailer, service := setUpAllServiceMocks(t)
+	return mockUserRepo, mockReddisRepo, mockMailer, service
+}
+
+func setUpAllServiceMocks(t *testing.T) (*moks.MockUserRepo, *moks.MockSessionRepo, *moks.MockRedisRepo, *moks.MockMailer, *AuthService) {
 	mockUserRepo := new(moks.MockUserRepo)
+	mockSessionRepo := new(moks.MockSessionRepo)
 	mockReddisRepo := new(moks.MockRedisRepo)
-	return mockUserRepo, mockReddisRepo, NewAuthService(mockUserRepo, mockReddisRepo)
+	mockMailer := new(moks.MockMailer)
+	conf := &configs.Config{
+		Auth: configs.AuthConfig{
+			SecretAcc:       "acc-secret",
+			SecretRef:       "ref-secret",
+			AccessTokenExp:  time.Minute,
+			RefreshTokenExp: time.Hour,
+			VerifyTokenExp:  time.Hour,
+			LocalCacheTTL:   time.Minute,
+
+			LoginMaxAttempts:      5,
+			LoginMaxAttemptsPerIP: 20,
+			LoginAttemptWindow:    15 * time.Minute,
+			LoginLockout:          time.Minute,
+			LoginMaxLockout:       time.Hour,
+		},
+		Mail: configs.MailConfig{BaseURL: "http://localhost:8080", ResetURL: "http://localhost:3000/reset-password"},
+	}
+	tokens := jwt_stuff.NewJWT(conf.Auth.SecretAcc, conf.Auth.SecretRef, conf.Auth.AccessTokenExp, conf.Auth.RefreshTokenExp)
+	service := NewAuthService(mockUserRepo, mockSessionRepo, mockReddisRepo, tokens, mockMailer, conf, logger.Nop())
+	return mockUserRepo, mockSessionRepo, mockReddisRepo, mockMailer, service
 }
 
 // тест для метода Register у слоя Service
 func TestAuthService_Register(t *testing.T) {
 
 	t.Run("Successfull registration", func(t *testing.T) {
-		mockUserRepo, _, service := setUpServiceTest(t)
+		mockUserRepo, mockReddisRepo, mockMailer, service := setUpMailServiceTest(t)
 
 		mockUserRepo.On("CheckIfInBaseByEmail", mock.Anything, "test@example.com").Return(false, nil)
-		mockUserRepo.On("AddUser", mock.Anything, "test@example.com", mock.Anything).Return(nil)
+		mockUserRepo.On("AddUser", mock.Anything, "test@example.com", mock.Anything, "user", false).Return(7, nil)
+		mockReddisRepo.On("Set", mock.Anything, mock.MatchedBy(func(key string) bool {
+			return strings.HasPrefix(key, "email_verify:")
+		}), "7", time.Hour).Return(nil)
+		mockMailer.On("Send", mock.Anything, mock.MatchedBy(func(msg mailer.Message) bool {
+			return msg.To == "test@example.com" && strings.Contains(msg.Body, "http://localhost:8080/verify?token=")
+		})).Return(nil)
 
 		err := service.Register(context.Background(), "test@example.com", "password123")
 
 		assert.NoError(t, err)
 		mockUserRepo.AssertExpectations(t)
+		mockReddisRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	// без письма пользователь удаляется, чтобы регистрацию можно было повторить с тем же email
+	t.Run("mail delivery failed", func(t *testing.T) {
+		mockUserRepo, mockReddisRepo, mockMailer, service := setUpMailServiceTest(t)
+
+		mockUserRepo.On("CheckIfInBaseByEmail", mock.Anything, "test@example.com").Return(false, nil)
+		mockUserRepo.On("AddUser", mock.Anything, "test@example.com", mock.Anything, "user", false).Return(7, nil)
+		mockReddisRepo.On("Set", mock.Anything, mock.Anything, "7", time.Hour).Return(nil)
+		mockMailer.On("Send", mock.Anything, mock.Anything).Return(errors.New("smtp unavailable"))
+		mockReddisRepo.On("Del", mock.Anything, mock.MatchedBy(func(keys []string) bool {
+			return len(keys) == 1 && strings.HasPrefix(keys[0], "email_verify:")
+		})).Return(nil)
+		mockUserRepo.On("DeleteUserById", mock.Anything, 7).Return(&users.User{Id: 7}, nil)
+
+		err := service.Register(context.Background(), "test@example.com", "password123")
+
+		assert.ErrorContains(t, err, "failed to send verification email")
+		mockUserRepo.AssertExpectations(t)
+		mockReddisRepo.AssertExpectations(t)
+	})
+
+	t.Run("token store failed", func(t *testing.T) {
+		mockUserRepo, mockReddisRepo, mockMailer, service := setUpMailServiceTest(t)
+
+		mockUserRepo.On("CheckIfInBaseByEmail", mock.Anything, "test@example.com").Return(false, nil)
+		mockUserRepo.On("AddUser", mock.Anything, "test@example.com", mock.Anything, "user", false).Return(7, nil)
+		mockReddisRepo.On("Set", mock.Anything, mock.Anything, "7", time.Hour).Return(errors.New("redis is down"))
+		mockUserRepo.On("DeleteUserById", mock.Anything, 7).Return(&users.User{Id: 7}, nil)
+
+		err := service.Register(context.Background(), "test@example.com", "password123")
+
+		assert.Error(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
 	})
 
 	t.Run("user already exists", func(t *testing.T) {
@@ -57,29 +154,62 @@ func TestAuthService_Register(t *testing.T) {
 	})
 }
 
+// ожидание проверки блокировок логина: ни email, ни IP не заблокированы
+func expectNoLoginLock(mockReddisRepo *moks.MockRedisRepo) {
+	mockReddisRepo.On("TTL", mock.Anything, mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, "login_lock:")
+	})).Return(time.Duration(0), db.ErrKeyNotFound)
+}
+
 // тест для метода Login у слоя Service
 func TestAuthService_Login(t *testing.T) {
 	t.Run("successful login", func(t *testing.T) {
-		mockUserRepo, _, service := setUpServiceTest(t)
+		mockUserRepo, mockReddisRepo, service := setUpServiceTest(t)
 
 		// Хешированный пароль для теста
-		hashedPassword := "$2a$10$N9qo8uLOickgx2ZMRZoMy.Mrq1V8H3M3kL6h7pW1pJ5Qn6T7XzB1O"
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+		assert.NoError(t, err)
 
 		mockUser := &users.User{
-			Email:    "test@example.com",
-			HashPass: hashedPassword,
+			Email:         "test@example.com",
+			HashPass:      string(hashedPassword),
+			IsActive:      true,
+			EmailVerified: true,
 		}
 
+		expectNoLoginLock(mockReddisRepo)
 		mockUserRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(mockUser, nil)
+		mockReddisRepo.On("Del", mock.Anything, []string{"login_fail:email:test@example.com", "login_lockouts:email:test@example.com"}).Return(nil)
 
-		err := service.Login(context.Background(), "test@example.com", "password123")
+		err = service.Login(context.Background(), "test@example.com", "password123", "10.0.0.1")
 
 		assert.NoError(t, err)
 		mockUserRepo.AssertExpectations(t)
+		mockReddisRepo.AssertExpectations(t)
+	})
+
+	t.Run("email not verified", func(t *testing.T) {
+		mockUserRepo, mockReddisRepo, service := setUpServiceTest(t)
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+		assert.NoError(t, err)
+
+		mockUser := &users.User{
+			Email:    "test@example.com",
+			HashPass: string(hashedPassword),
+		}
+
+		expectNoLoginLock(mockReddisRepo)
+		mockUserRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(mockUser, nil)
+		mockReddisRepo.On("Del", mock.Anything, mock.Anything).Return(nil)
+
+		err = service.Login(context.Background(), "test@example.com", "password123", "10.0.0.1")
+
+		assert.ErrorIs(t, err, ErrEmailNotVerified)
 	})
 
 	t.Run("wrong password", func(t *testing.T) {
-		mockUserRepo, _, service := setUpServiceTest(t)
+		mockUserRepo, mockReddisRepo, service := setUpServiceTest(t)
 
 		// Хешированный пароль для теста
 		hashedPassword := "$2a$10$N9qo8uLOickgx2ZMRZoMy.Mrq1V8H3M3kL6h7pW1pJ5Qn6T7XzB1O"
@@ -89,12 +219,812 @@ func TestAuthService_Login(t *testing.T) {
 			HashPass: hashedPassword,
 		}
 
+		expectNoLoginLock(mockReddisRepo)
 		mockUserRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(mockUser, nil)
+		mockReddisRepo.On("Incr", mock.Anything, "login_fail:email:test@example.com").Return(int64(1), nil)
+		mockReddisRepo.On("Incr", mock.Anything, "login_fail:ip:10.0.0.1").Return(int64(1), nil)
+		mockReddisRepo.On("Expire", mock.Anything, mock.Anything, 15*time.Minute).Return(nil)
 
-		err := service.Login(context.Background(), "test@example.com", "wrongpassword")
+		err := service.Login(context.Background(), "test@example.com", "wrongpassword", "10.0.0.1")
 
 		assert.Error(t, err)
 		assert.Equal(t, users.ErrWrongCredentials, err.Error())
 		mockUserRepo.AssertExpectations(t)
+		mockReddisRepo.AssertExpectations(t)
+	})
+}
+
+// тест защиты от перебора паролей: блокировка после лимита неудачных попыток, 2-я блокировка вдвое дольше
+func TestAuthService_LoginLockout(t *testing.T) {
+	t.Run("locked email", func(t *testing.T) {
+		mockUserRepo, mockReddisRepo, service := setUpServiceTest(t)
+
+		mockReddisRepo.On("TTL", mock.Anything, "login_lock:email:test@example.com").Return(30*time.Second, nil)
+
+		err := service.Login(context.Background(), "Test@Example.com", "password123", "10.0.0.1")
+
+		var locked *LoginLockedError
+		assert.ErrorAs(t, err, &locked)
+		assert.Equal(t, 30*time.Second, locked.RetryAfter)
+		assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+		mockUserRepo.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("last allowed attempt failed", func(t *testing.T) {
+		mockUserRepo, mockReddisRepo, service := setUpServiceTest(t)
+
+		expectNoLoginLock(mockReddisRepo)
+		mockUserRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&users.User{Email: "test@example.com", HashPass: "x"}, nil)
+		mockReddisRepo.On("Incr", mock.Anything, "login_fail:email:test@example.com").Return(int64(5), nil)
+		mockReddisRepo.On("Incr", mock.Anything, "login_fail:ip:10.0.0.1").Return(int64(5), nil)
+		mockReddisRepo.On("Expire", mock.Anything, mock.Anything, 15*time.Minute).Return(nil)
+		mockReddisRepo.On("Incr", mock.Anything, "login_lockouts:email:test@example.com").Return(int64(2), nil)
+		mockReddisRepo.On("Expire", mock.Anything, "login_lockouts:email:test@example.com", 24*time.Hour).Return(nil)
+		mockReddisRepo.On("Set", mock.Anything, "login_lock:email:test@example.com", "locked", 2*time.Minute).Return(nil)
+		mockReddisRepo.On("Del", mock.Anything, []string{"login_fail:email:test@example.com"}).Return(nil)
+
+		err := service.Login(context.Background(), "test@example.com", "wrongpassword", "10.0.0.1")
+
+		var locked *LoginLockedError
+		assert.ErrorAs(t, err, &locked)
+		assert.Equal(t, 2*time.Minute, locked.RetryAfter)
+		mockReddisRepo.AssertExpectations(t)
+	})
+
+	t.Run("redis unavailable", func(t *testing.T) {
+		mockUserRepo, mockReddisRepo, service := setUpServiceTest(t)
+
+		mockReddisRepo.On("TTL", mock.Anything, mock.Anything).Return(time.Duration(0), errors.New("connection refused"))
+		mockUserRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&users.User{Email: "test@example.com", HashPass: "x"}, nil)
+		mockReddisRepo.On("Incr", mock.Anything, mock.Anything).Return(int64(0), errors.New("connection refused"))
+
+		err := service.Login(context.Background(), "test@example.com", "wrongpassword", "10.0.0.1")
+
+		assert.EqualError(t, err, users.ErrWrongCredentials)
+	})
+}
+
+// тест длительности блокировок: удваивается до максимума
+func TestLockoutDuration(t *testing.T) {
+	assert.Equal(t, time.Minute, lockoutDuration(time.Minute, time.Hour, 1))
+	assert.Equal(t, 4*time.Minute, lockoutDuration(time.Minute, time.Hour, 3))
+	assert.Equal(t, time.Hour, lockoutDuration(time.Minute, time.Hour, 10))
+	assert.Equal(t, time.Hour, lockoutDuration(time.Minute, time.Hour, 1000))
+}
+
+// тест снятия блокировки логина администратором
+func TestAuthService_UnlockAccount(t *testing.T) {
+	mockUserRepo, mockReddisRepo, service := setUpServiceTest(t)
+
+	mockUserRepo.On("FindById", mock.Anything, 7).Return(&users.User{Id: 7, Email: "Test@example.com"}, nil)
+	mockReddisRepo.On("Del", mock.Anything, []string{
+		"login_lock:email:test@example.com",
+		"login_fail:email:test@example.com",
+		"login_lockouts:email:test@example.com",
+	}).Return(nil)
+
+	err := service.UnlockAccount(context.Background(), 7)
+
+	assert.NoError(t, err)
+	mockReddisRepo.AssertExpectations(t)
+}
+
+// тест подтверждения email: токен из письма одноразовый, после подтверждения пользователь активен
+func TestAuthService_VerifyEmail(t *testing.T) {
+	t.Run("valid token", func(t *testing.T) {
+		mockUserRepo, mockReddisRepo, _, service := setUpMailServiceTest(t)
+		token, jti, err := service.tokens.GenerateVerificationToken("test@example.com", "7", time.Hour)
+		assert.NoError(t, err)
+
+		mockReddisRepo.On("GetDel", mock.Anything, "email_verify:"+jti).Return("7", nil)
+		mockUserRepo.On("VerifyEmail", mock.Anything, 7).Return(nil)
+		mockReddisRepo.On("Set", mock.Anything, "token_state:7", invalidationMarker, time.Minute).Return(nil)
+
+		err = service.VerifyEmail(context.Background(), token)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockReddisRepo.AssertExpectations(t)
+	})
+
+	t.Run("already used token", func(t *testing.T) {
+		mockUserRepo, mockReddisRepo, _, service := setUpMailServiceTest(t)
+		token, jti, err := service.tokens.GenerateVerificationToken("test@example.com", "7", time.Hour)
+		assert.NoError(t, err)
+
+		mockReddisRepo.On("GetDel", mock.Anything, "email_verify:"+jti).Return("", db.ErrKeyNotFound)
+
+		err = service.VerifyEmail(context.Background(), token)
+
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+		mockUserRepo.AssertNotCalled(t, "VerifyEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		_, _, _, service := setUpMailServiceTest(t)
+		token, _, err := service.tokens.GenerateVerificationToken("test@example.com", "7", -time.Minute)
+		assert.NoError(t, err)
+
+		err = service.VerifyEmail(context.Background(), token)
+
+		assert.ErrorIs(t, err, ErrVerificationTokenExpired)
+	})
+
+	t.Run("access token instead of verification token", func(t *testing.T) {
+		_, _, _, service := setUpMailServiceTest(t)
+		accessToken, _, err := service.tokens.GenerateTokens("test@example.com", "7", "user", true, 0)
+		assert.NoError(t, err)
+
+		err = service.VerifyEmail(context.Background(), accessToken)
+
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+}
+
+// тест запроса сброса пароля: письмо отправляется в фоне и только зарегистрированному пользователю, в БД - только хэш токена
+func TestAuthService_ForgotPassword(t *testing.T) {
+	t.Run("registered email", func(t *testing.T) {
+		mockUserRepo, _, mockMailer, service := setUpMailServiceTest(t)
+
+		var sentToken string
+		mockUserRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&users.User{Id: 7, Email: "test@example.com"}, nil)
+		mockUserRepo.On("CreatePasswordReset", mock.Anything, 7, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+		mockMailer.On("Send", mock.Anything, mock.MatchedBy(func(msg mailer.Message) bool {
+			return msg.To == "test@example.com"
+		})).Run(func(args mock.Arguments) {
+			body := args.Get(1).(mailer.Message).Body
+			_, link, _ := strings.Cut(body, "http://localhost:3000/reset-password?token=")
+			sentToken, _, _ = strings.Cut(link, "\n")
+		}).Return(nil)
+
+		err := service.ForgotPassword(context.Background(), "test@example.com")
+
+		assert.NoError(t, err)
+		assert.NoError(t, service.Wait(context.Background()))
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+		storedHash := mockUserRepo.Calls[1].Arguments.String(2)
+		assert.NotEqual(t, sentToken, storedHash)
+		assert.Equal(t, hashResetToken(sentToken), storedHash)
+	})
+
+	t.Run("page query is kept", func(t *testing.T) {
+		link, err := resetLink("https://app.example.com/#/reset?lang=ru", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://app.example.com/?token=abc#/reset?lang=ru", link)
+
+		link, err = resetLink("https://example.com/reset?lang=ru", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/reset?lang=ru&token=abc", link)
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		mockUserRepo, _, mockMailer, service := setUpMailServiceTest(t)
+
+		mockUserRepo.On("FindByEmail", mock.Anything, "unknown@example.com").Return((*users.User)(nil), errors.New(users.ErrUserNotExists))
+
+		err := service.ForgotPassword(context.Background(), "unknown@example.com")
+
+		assert.NoError(t, err)
+		assert.NoError(t, service.Wait(context.Background()))
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	// время ответа не зависит от наличия email в базе: запрос не ждёт записи токена и письма
+	t.Run("response does not wait for email", func(t *testing.T) {
+		mockUserRepo, _, mockMailer, service := setUpMailServiceTest(t)
+
+		release := make(chan struct{})
+		mockUserRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&users.User{Id: 7, Email: "test@example.com"}, nil)
+		mockUserRepo.On("CreatePasswordReset", mock.Anything, 7, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+		mockMailer.On("Send", mock.Anything, mock.Anything).Run(func(mock.Arguments) { <-release }).Return(nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		err := service.ForgotPassword(ctx, "test@example.com")
+		cancel() // ответ отправлен, контекст запроса отменён
+
+		assert.NoError(t, err)
+		waitCtx, waitCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer waitCancel()
+		assert.ErrorIs(t, service.Wait(waitCtx), context.DeadlineExceeded)
+
+		close(release)
+		assert.NoError(t, service.Wait(context.Background()))
+		mockMailer.AssertExpectations(t)
+	})
+}
+
+// тест сброса пароля: токен одноразовый, после смены пароля все токены пользователя отзываются
+func TestAuthService_ResetPassword(t *testing.T) {
+	t.Run("valid token", func(t *testing.T) {
+		mockUserRepo, mockSessionRepo, mockReddisRepo, _, service := setUpAllServiceMocks(t)
+
+		mockUserRepo.On("ConsumePasswordReset", mock.Anything, hashResetToken("reset-token")).Return(7, time.Now().Add(time.Hour), nil)
+		mockUserRepo.On("UpdatePassword", mock.Anything, 7, mock.MatchedBy(func(hash string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")) == nil
+		})).Return(nil)
+		mockUserRepo.On("IncrementTokenVersion", mock.Anything, 7).Return(1, nil)
+		mockReddisRepo.On("Set", mock.Anything, "token_state:7", invalidationMarker, time.Minute).Return(nil)
+		mockSessionRepo.On("DeleteUserSessions", mock.Anything, 7).Return(nil)
+
+		err := service.ResetPassword(context.Background(), "reset-token", "new-password")
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockReddisRepo.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	// пароль уже сменён, поэтому сбой отзыва сессий не превращается в ошибку запроса
+	t.Run("revoking sessions failed", func(t *testing.T) {
+		mockUserRepo, _, _, service := setUpMailServiceTest(t)
+
+		mockUserRepo.On("ConsumePasswordReset", mock.Anything, hashResetToken("reset-token")).Return(7, time.Now().Add(time.Hour), nil)
+		mockUserRepo.On("UpdatePassword", mock.Anything, 7, mock.Anything).Return(nil)
+		mockUserRepo.On("IncrementTokenVersion", mock.Anything, 7).Return(0, errors.New("db is down"))
+
+		err := service.ResetPassword(context.Background(), "reset-token", "new-password")
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("used token", func(t *testing.T) {
+		mockUserRepo, _, _, service := setUpMailServiceTest(t)
+
+		mockUserRepo.On("ConsumePasswordReset", mock.Anything, hashResetToken("reset-token")).Return(0, time.Time{}, users.ErrResetTokenNotFound)
+
+		err := service.ResetPassword(context.Background(), "reset-token", "new-password")
+
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		mockUserRepo, _, _, service := setUpMailServiceTest(t)
+
+		mockUserRepo.On("ConsumePasswordReset", mock.Anything, hashResetToken("reset-token")).Return(7, time.Now().Add(-time.Minute), nil)
+
+		err := service.ResetPassword(context.Background(), "reset-token", "new-password")
+
+		assert.ErrorIs(t, err, ErrResetTokenExpired)
+		mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// тест смены пароля: нужен верный текущий пароль (неверный учитывается блокировкой логина),
+// все токены пользователя отзываются, остальные сессии закрываются, текущей сессии выдаются новые токены
+func TestAuthService_ChangePassword(t *testing.T) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("old-password!"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &users.User{Id: 7, Email: "test@example.com", Role: "user", IsActive: true, HashPass: string(hashedPassword)}
+
+	t.Run("password changed", func(t *testing.T) {
+		mockUserRepo, mockSessionRepo, mockReddisRepo, service := setUpServiceMocks(t)
+
+		expectNoLoginLock(mockReddisRepo)
+		mockUserRepo.On("FindById", mock.Anything, 7).Return(user, nil)
+		mockReddisRepo.On("Del", mock.Anything, []string{"login_fail:email:test@example.com", "login_lockouts:email:test@example.com"}).Return(nil)
+		mockUserRepo.On("UpdatePassword", mock.Anything, 7, mock.MatchedBy(func(hash string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password!")) == nil
+		})).Return(nil)
+		mockUserRepo.On("IncrementTokenVersion", mock.Anything, 7).Return(3, nil)
+		mockReddisRepo.On("Set", mock.Anything, "token_state:7", invalidationMarker, time.Minute).Return(nil)
+		mockSessionRepo.On("DeleteOtherUserSessions", mock.Anything, 7, testSessionId).Return(nil)
+		mockSessionRepo.On("GetSessionById", mock.Anything, testSessionId).Return(&sessions.Session{ID: testSessionId, UserID: 7, Jti: "old-jti"}, nil)
+		mockSessionRepo.On("RotateSession", mock.Anything, testSessionId, "old-jti", mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(true, nil)
+
+		accessToken, refreshToken, err := service.ChangePassword(context.Background(), 7, testSessionId, "old-password!", "new-password!", "10.0.0.1")
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+		mockReddisRepo.AssertExpectations(t)
+
+		// новые токены - новой версии и в той же сессии, jti нового refresh токена сохранён в сессии
+		access, err := service.tokens.AccessVerifier().Verify(context.Background(), accessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, access.Version)
+		refresh, err := service.tokens.RefreshVerifier().Verify(context.Background(), refreshToken)
+		assert.NoError(t, err)
+		assert.Equal(t, testSessionId, refresh.FamilyId)
+		assert.Equal(t, refresh.ID, mockSessionRepo.Calls[2].Arguments.String(3))
+	})
+
+	t.Run("token without session id", func(t *testing.T) {
+		mockUserRepo, mockSessionRepo, mockReddisRepo, service := setUpServiceMocks(t)
+
+		expectNoLoginLock(mockReddisRepo)
+		mockUserRepo.On("FindById", mock.Anything, 7).Return(user, nil)
+		mockReddisRepo.On("Del", mock.Anything, mock.Anything).Return(nil)
+		mockUserRepo.On("UpdatePassword", mock.Anything, 7, mock.Anything).Return(nil)
+		mockUserRepo.On("IncrementTokenVersion", mock.Anything, 7).Return(3, nil)
+		mockReddisRepo.On("Set", mock.Anything, "token_state:7", invalidationMarker, time.Minute).Return(nil)
+		mockSessionRepo.On("DeleteUserSessions", mock.Anything, 7).Return(nil)
+
+		accessToken, refreshToken, err := service.ChangePassword(context.Background(), 7, "", "old-password!", "new-password!", "10.0.0.1")
+
+		assert.NoError(t, err)
+		assert.Empty(t, accessToken)
+		assert.Empty(t, refreshToken)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong current password is counted as failed login", func(t *testing.T) {
+		mockUserRepo, _, mockReddisRepo, service := setUpServiceMocks(t)
+
+		expectNoLoginLock(mockReddisRepo)
+		mockUserRepo.On("FindById", mock.Anything, 7).Return(user, nil)
+		mockReddisRepo.On("Incr", mock.Anything, "login_fail:email:test@example.com").Return(int64(1), nil)
+		mockReddisRepo.On("Incr", mock.Anything, "login_fail:ip:10.0.0.1").Return(int64(1), nil)
+		mockReddisRepo.On("Expire", mock.Anything, mock.Anything, 15*time.Minute).Return(nil)
+
+		_, _, err := service.ChangePassword(context.Background(), 7, testSessionId, "guess-password!", "new-password!", "10.0.0.1")
+
+		assert.ErrorIs(t, err, ErrWrongPassword)
+		mockReddisRepo.AssertExpectations(t)
+		mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		mockUserRepo, _, mockReddisRepo, service := setUpServiceMocks(t)
+
+		mockUserRepo.On("FindById", mock.Anything, 7).Return(user, nil)
+		mockReddisRepo.On("TTL", mock.Anything, "login_lock:email:test@example.com").Return(time.Minute, nil)
+
+		_, _, err := service.ChangePassword(context.Background(), 7, testSessionId, "old-password!", "new-password!", "10.0.0.1")
+
+		assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+		mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("same password", func(t *testing.T) {
+		mockUserRepo, _, mockReddisRepo, service := setUpServiceMocks(t)
+
+		expectNoLoginLock(mockReddisRepo)
+		mockUserRepo.On("FindById", mock.Anything, 7).Return(user, nil)
+		mockReddisRepo.On("Del", mock.Anything, mock.Anything).Return(nil)
+
+		_, _, err := service.ChangePassword(context.Background(), 7, testSessionId, "old-password!", "old-password!", "10.0.0.1")
+
+		assert.ErrorIs(t, err, ErrSamePassword)
+	})
+}
+
+// тест для метода DeleteUser у слоя Service
+func TestAuthService_DeleteUser(t *testing.T) {
+	t.Run("existing user", func(t *testing.T) {
+		mockUserRepo, mockReddisRepo, service := setUpServiceTest(t)
+
+		mockUserRepo.On("DeleteUserById", mock.Anything, 5).Return(&users.User{Id: 5}, nil)
+		mockReddisRepo.On("Set", mock.Anything, "token_state:5", invalidationMarker, time.Minute).Return(nil)
+
+		err := service.DeleteUser(context.Background(), 5)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockReddisRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		mockUserRepo, _, service := setUpServiceTest(t)
+
+		mockUserRepo.On("DeleteUserById", mock.Anything, 42).Return(nil, users.ErrUserNotFound)
+
+		err := service.DeleteUser(context.Background(), 42)
+
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+	})
+}
+
+const testSessionId = "0b7d5f0e-3f4a-4c1e-9a57-1d2a6c8e9f10"
+
+// выдаёт refresh токен сессии testSessionId и его claims
+func newTestRefreshToken(t *testing.T) (string, *jwt_stuff.CustomClaims) {
+	_, refreshToken, err := jwt_stuff.NewJWT("acc", "ref", time.Minute, time.Hour).
+		GenerateTokensInFamily("test@example.com", "5", "user", true, 0, testSessionId)
+	assert.NoError(t, err)
+	claims, err := jwt_stuff.ParseTokenWithoutVerification(refreshToken)
+	assert.NoError(t, err)
+	return refreshToken, claims
+}
+
+// тест для метода CreateSession у слоя Service
+func TestAuthService_CreateSession(t *testing.T) {
+	_, mockSessionRepo, service := setUpSessionServiceTest(t)
+	token, claims := newTestRefreshToken(t)
+	client := sessions.ClientInfo{DeviceName: "phone", UserAgent: "test-agent", IP: "10.0.0.1"}
+
+	mockSessionRepo.On("CreateSession", mock.Anything, mock.MatchedBy(func(s *sessions.Session) bool {
+		return s.ID == testSessionId && s.UserID == 5 && s.Jti == claims.ID &&
+			s.DeviceName == "phone" && s.UserAgent == "test-agent" && s.IP == "10.0.0.1" &&
+			s.ExpiresAt.Equal(claims.ExpiresAt.Time)
+	})).Return(nil)
+
+	err := service.CreateSession(context.Background(), 5, token, client)
+
+	assert.NoError(t, err)
+	mockSessionRepo.AssertExpectations(t)
+}
+
+// тест обрезки слишком длинного User-Agent до размера колонки, чтобы логин не падал на вставке сессии
+func TestAuthService_CreateSession_LongUserAgent(t *testing.T) {
+	_, mockSessionRepo, service := setUpSessionServiceTest(t)
+	token, _ := newTestRefreshToken(t)
+	client := sessions.ClientInfo{UserAgent: strings.Repeat("ю", 2000)}
+
+	mockSessionRepo.On("CreateSession", mock.Anything, mock.MatchedBy(func(s *sessions.Session) bool {
+		return s.UserAgent == strings.Repeat("ю", sessions.MaxUserAgentLen)
+	})).Return(nil)
+
+	err := service.CreateSession(context.Background(), 5, token, client)
+
+	assert.NoError(t, err)
+	mockSessionRepo.AssertExpectations(t)
+}
+
+// тест для метода ValidateRefreshToken у слоя Service
+func TestAuthService_ValidateRefreshToken(t *testing.T) {
+	t.Run("current token of session", func(t *testing.T) {
+		mockUserRepo, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, claims := newTestRefreshToken(t)
+
+		mockSessionRepo.On("GetSessionById", mock.Anything, testSessionId).Return(&sessions.Session{ID: testSessionId, UserID: 5, Jti: claims.ID}, nil)
+		mockUserRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&users.User{Id: 5, Role: "user"}, nil)
+
+		user, err := service.ValidateRefreshToken(context.Background(), *claims)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, user.Id)
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, claims := newTestRefreshToken(t)
+
+		mockSessionRepo.On("GetSessionById", mock.Anything, testSessionId).Return(nil, sessions.ErrSessionNotFound)
+
+		_, err := service.ValidateRefreshToken(context.Background(), *claims)
+
+		assert.ErrorIs(t, err, ErrRefreshTokenRevoked)
+	})
+
+	t.Run("reuse of rotated token revokes session", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, oldClaims := newTestRefreshToken(t)
+		_, currentClaims := newTestRefreshToken(t)
+
+		mockSessionRepo.On("GetSessionById", mock.Anything, testSessionId).Return(&sessions.Session{ID: testSessionId, UserID: 5, Jti: currentClaims.ID}, nil)
+		mockSessionRepo.On("DeleteSession", mock.Anything, testSessionId).Return(nil)
+
+		_, err := service.ValidateRefreshToken(context.Background(), *oldClaims)
+
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("token version changed by logout from all devices", func(t *testing.T) {
+		mockUserRepo, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, claims := newTestRefreshToken(t)
+
+		mockSessionRepo.On("GetSessionById", mock.Anything, testSessionId).Return(&sessions.Session{ID: testSessionId, UserID: 5, Jti: claims.ID}, nil)
+		mockUserRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&users.User{Id: 5, Role: "user", TokenVersion: 1}, nil)
+
+		_, err := service.ValidateRefreshToken(context.Background(), *claims)
+
+		assert.ErrorIs(t, err, ErrRefreshTokenRevoked)
+	})
+
+	t.Run("session of another user", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, claims := newTestRefreshToken(t)
+
+		mockSessionRepo.On("GetSessionById", mock.Anything, testSessionId).Return(&sessions.Session{ID: testSessionId, UserID: 7, Jti: claims.ID}, nil)
+
+		_, err := service.ValidateRefreshToken(context.Background(), *claims)
+
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		mockSessionRepo.AssertNotCalled(t, "DeleteSession", mock.Anything, mock.Anything)
+	})
+
+	t.Run("token issued before sessions", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, claims := newTestRefreshToken(t)
+		claims.FamilyId = ""
+
+		_, err := service.ValidateRefreshToken(context.Background(), *claims)
+
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		mockSessionRepo.AssertNotCalled(t, "GetSessionById", mock.Anything, mock.Anything)
+	})
+}
+
+// тест для метода RotateRefreshToken у слоя Service
+func TestAuthService_RotateRefreshToken(t *testing.T) {
+	t.Run("session gets new token", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, oldClaims := newTestRefreshToken(t)
+		newToken, newClaims := newTestRefreshToken(t)
+
+		mockSessionRepo.On("RotateSession", mock.Anything, testSessionId, oldClaims.ID, newClaims.ID, newClaims.ExpiresAt.Time).Return(true, nil)
+
+		err := service.RotateRefreshToken(context.Background(), *oldClaims, newToken)
+
+		assert.NoError(t, err)
+		mockSessionRepo.AssertExpectations(t)
+		mockSessionRepo.AssertNotCalled(t, "DeleteSession", mock.Anything, mock.Anything)
+	})
+
+	t.Run("concurrent rotation of the same token", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, oldClaims := newTestRefreshToken(t)
+		newToken, _ := newTestRefreshToken(t)
+
+		mockSessionRepo.On("RotateSession", mock.Anything, testSessionId, oldClaims.ID, mock.Anything, mock.Anything).Return(false, nil)
+		mockSessionRepo.On("DeleteSession", mock.Anything, testSessionId).Return(nil)
+
+		err := service.RotateRefreshToken(context.Background(), *oldClaims, newToken)
+
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		mockSessionRepo.AssertExpectations(t)
+	})
+}
+
+// тест для методов InvalidateRefreshToken и RevokeSession у слоя Service
+func TestAuthService_EndSession(t *testing.T) {
+	t.Run("logout deletes session of token", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, claims := newTestRefreshToken(t)
+
+		mockSessionRepo.On("DeleteUserSessionByJti", mock.Anything, 5, claims.ID).Return(nil)
+
+		err := service.InvalidateRefreshToken(context.Background(), 5, *claims)
+
+		assert.NoError(t, err)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("repeated logout", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, claims := newTestRefreshToken(t)
+
+		mockSessionRepo.On("DeleteUserSessionByJti", mock.Anything, 5, claims.ID).Return(sessions.ErrSessionNotFound)
+
+		err := service.InvalidateRefreshToken(context.Background(), 5, *claims)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("logout with refresh token of another user", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+		_, claims := newTestRefreshToken(t)
+
+		err := service.InvalidateRefreshToken(context.Background(), 6, *claims)
+
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		mockSessionRepo.AssertNotCalled(t, "DeleteUserSessionByJti", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("revoke own session", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+
+		mockSessionRepo.On("DeleteUserSession", mock.Anything, 5, testSessionId).Return(nil)
+
+		err := service.RevokeSession(context.Background(), 5, testSessionId)
+
+		assert.NoError(t, err)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid session id", func(t *testing.T) {
+		_, mockSessionRepo, service := setUpSessionServiceTest(t)
+
+		err := service.RevokeSession(context.Background(), 5, "not-a-uuid")
+
+		assert.ErrorIs(t, err, sessions.ErrSessionNotFound)
+		mockSessionRepo.AssertNotCalled(t, "DeleteUserSession", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// значение, которым invalidateTokenState помечает сброс кэша состояния токенов
+var invalidationMarker = mock.MatchedBy(func(value interface{}) bool {
+	marker, ok := value.(string)
+	return ok && strings.HasPrefix(marker, tokenStateInvalidated)
+})
+
+// тест для метода LogoutAll у слоя Service
+func TestAuthService_LogoutAll(t *testing.T) {
+	t.Run("version is incremented, cache reset, sessions deleted", func(t *testing.T) {
+		mockUserRepo, mockSessionRepo, mockRedisRepo, service := setUpServiceMocks(t)
+
+		// состояние токенов уже в in-process кэше
+		mockRedisRepo.On("Get", mock.Anything, "token_state:5").Return("2:true", nil).Once()
+		_, _, err := service.TokenState(context.Background(), 5)
+		assert.NoError(t, err)
+
+		mockUserRepo.On("IncrementTokenVersion", mock.Anything, 5).Return(3, nil)
+		mockRedisRepo.On("Set", mock.Anything, "token_state:5", invalidationMarker, time.Minute).Return(nil)
+		mockSessionRepo.On("DeleteUserSessions", mock.Anything, 5).Return(nil)
+
+		err = service.LogoutAll(context.Background(), 5)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+
+		// после сброса кэша новая версия читается из БД
+		mockRedisRepo.On("Get", mock.Anything, "token_state:5").Return("", db.ErrKeyNotFound)
+		mockUserRepo.On("GetTokenState", mock.Anything, 5).Return(3, true, nil)
+		mockRedisRepo.On("CompareAndSet", mock.Anything, "token_state:5", "", "3:true", time.Minute).Return(true, nil)
+
+		version, _, err := service.TokenState(context.Background(), 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, version)
+	})
+
+	t.Run("cache reset failure is reported", func(t *testing.T) {
+		mockUserRepo, mockSessionRepo, mockRedisRepo, service := setUpServiceMocks(t)
+
+		mockUserRepo.On("IncrementTokenVersion", mock.Anything, 5).Return(3, nil)
+		mockRedisRepo.On("Set", mock.Anything, "token_state:5", invalidationMarker, time.Minute).Return(errors.New("redis down"))
+
+		err := service.LogoutAll(context.Background(), 5)
+
+		assert.Error(t, err)
+		mockSessionRepo.AssertNotCalled(t, "DeleteUserSessions", mock.Anything, mock.Anything)
+	})
+}
+
+// тест для метода TokenState у слоя Service
+func TestAuthService_TokenState(t *testing.T) {
+	t.Run("redis value is cached in process", func(t *testing.T) {
+		mockUserRepo, _, mockRedisRepo, service := setUpServiceMocks(t)
+
+		mockRedisRepo.On("Get", mock.Anything, "token_state:5").Return("2:false", nil).Once()
+
+		for i := 0; i < 3; i++ {
+			version, isActive, err := service.TokenState(context.Background(), 5)
+
+			assert.NoError(t, err)
+			assert.Equal(t, 2, version)
+			assert.False(t, isActive)
+		}
+		mockRedisRepo.AssertExpectations(t)
+		mockUserRepo.AssertNotCalled(t, "GetTokenState", mock.Anything, mock.Anything)
+	})
+
+	t.Run("cache miss reads DB and fills cache", func(t *testing.T) {
+		mockUserRepo, _, mockRedisRepo, service := setUpServiceMocks(t)
+
+		mockRedisRepo.On("Get", mock.Anything, "token_state:5").Return("", db.ErrKeyNotFound).Once()
+		mockUserRepo.On("GetTokenState", mock.Anything, 5).Return(1, true, nil).Once()
+		mockRedisRepo.On("CompareAndSet", mock.Anything, "token_state:5", "", "1:true", time.Minute).Return(true, nil).Once()
+
+		version, isActive, err := service.TokenState(context.Background(), 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, version)
+		assert.True(t, isActive)
+		mockRedisRepo.AssertExpectations(t)
+	})
+
+	t.Run("redis unavailable", func(t *testing.T) {
+		mockUserRepo, _, mockRedisRepo, service := setUpServiceMocks(t)
+
+		mockRedisRepo.On("Get", mock.Anything, "token_state:5").Return("", errors.New("redis down"))
+		mockUserRepo.On("GetTokenState", mock.Anything, 5).Return(1, true, nil)
+
+		version, _, err := service.TokenState(context.Background(), 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, version)
+		mockRedisRepo.AssertNotCalled(t, "CompareAndSet", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// Redis в памяти для тестов гонок: Get, Set и CompareAndSet с семантикой RedisRepo, остальные методы не используются
+type memRedis struct {
+	db.ReddisRepoInterface
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (r *memRedis) Get(ctx context.Context, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.values[key]
+	if !ok {
+		return "", db.ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (r *memRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[key] = value.(string)
+	return nil
+}
+
+func (r *memRedis) CompareAndSet(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.values[key] != old {
+		return false, nil
+	}
+	r.values[key] = value
+	return true, nil
+}
+
+// тест гонки TokenState и LogoutAll: logout со всех устройств на другом экземпляре выполняется
+// между чтением версии из БД и записью её в Redis, старая версия не должна попасть в кэш
+func TestAuthService_TokenState_LogoutAllRace(t *testing.T) {
+	redis := &memRedis{values: map[string]string{}}
+	mockUserRepo, mockSessionRepo, _, first := setUpServiceMocks(t)
+	first.redisRepo = redis
+	_, _, _, second := setUpServiceMocks(t)
+	second.repo, second.sessionRepo, second.redisRepo = mockUserRepo, mockSessionRepo, redis
+
+	mockUserRepo.On("IncrementTokenVersion", mock.Anything, 5).Return(3, nil)
+	mockSessionRepo.On("DeleteUserSessions", mock.Anything, 5).Return(nil)
+	mockUserRepo.On("GetTokenState", mock.Anything, 5).Return(2, true, nil).Once().Run(func(mock.Arguments) {
+		assert.NoError(t, second.LogoutAll(context.Background(), 5))
+	})
+	mockUserRepo.On("GetTokenState", mock.Anything, 5).Return(3, true, nil)
+
+	// запрос начался до logout и видит старую версию, но не кэширует её
+	version, _, err := first.TokenState(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version)
+	assert.True(t, strings.HasPrefix(redis.values["token_state:5"], tokenStateInvalidated))
+
+	// следующие проверки на обоих экземплярах видят новую версию
+	for _, service := range []*AuthService{first, second} {
+		version, _, err := service.TokenState(context.Background(), 5)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, version)
+	}
+	assert.Equal(t, "3:true", redis.values["token_state:5"])
+}
+
+// тест для методов RevokeAccessToken и IsAccessTokenRevoked у слоя Service
+func TestAuthService_AccessTokenDenylist(t *testing.T) {
+	t.Run("revoked token is known without redis round-trip", func(t *testing.T) {
+		_, _, mockRedisRepo, service := setUpServiceMocks(t)
+
+		mockRedisRepo.On("Set", mock.Anything, "access_token:jti-1", "revoked", mock.Anything).Return(nil)
+
+		err := service.RevokeAccessToken(context.Background(), "jti-1", time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+
+		revoked, err := service.IsAccessTokenRevoked(context.Background(), "jti-1")
+
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+		mockRedisRepo.AssertNotCalled(t, "Exists", mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired token is not stored", func(t *testing.T) {
+		_, _, mockRedisRepo, service := setUpServiceMocks(t)
+
+		err := service.RevokeAccessToken(context.Background(), "jti-1", time.Now().Add(-time.Minute))
+
+		assert.NoError(t, err)
+		mockRedisRepo.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("redis answer is cached in process", func(t *testing.T) {
+		_, _, mockRedisRepo, service := setUpServiceMocks(t)
+
+		mockRedisRepo.On("Exists", mock.Anything, "access_token:jti-2").Return(false, nil).Once()
+
+		for i := 0; i < 2; i++ {
+			revoked, err := service.IsAccessTokenRevoked(context.Background(), "jti-2")
+
+			assert.NoError(t, err)
+			assert.False(t, revoked)
+		}
+		mockRedisRepo.AssertExpectations(t)
 	})
 }
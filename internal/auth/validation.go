@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"net/mail"
+	"unicode"
+	"unicode/utf8"
+)
+
+// минимальная длина пароля
+const minPasswordLength = 8
+
+// Проверка email и пароля при регистрации
+func validateCredentials(email, password string) error {
+	if email == "" {
+		return ErrEmailRequired
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+
+	return validatePasswordStrength(password)
+}
+
+// Требования к надёжности пароля, общие для регистрации, смены и сброса пароля
+func validatePasswordStrength(password string) error {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	for _, r := range password {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) {
+			return nil
+		}
+	}
+	return ErrPasswordNoSpecialChar
+}
@@ -0,0 +1,10 @@
+package match
+
+import "errors"
+
+var (
+	ErrProfileNotFound = errors.New("profile of current user not found, create profile first")
+	ErrTargetNotFound  = errors.New("target profile not found")
+	ErrInvalidAction   = errors.New("invalid action, expected 'like', 'skip' or 'report'")
+	ErrActionConflict  = errors.New("another action has already been registered for this match")
+)
@@ -1,7 +1,11 @@
 package match
 
 import (
+	"errors"
+	"io"
+	"net/http"
 	"simple_gin_server/configs"
+	"simple_gin_server/pkg/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,14 +22,92 @@ func NewMatchHandler(service ServiceInterface, config *configs.Config) *MatchHan
 	}
 }
 
-// Хэндлер для вывода имен всех продуктов
-func (p *MatchHandler) SearchMatchesHandler(c *gin.Context) {}
+// Хэндлер поиска кандидатов в совпадения по заданным критериям
+func (p *MatchHandler) SearchMatchesHandler(c *gin.Context) {
+	// пустое тело запроса - поиск без фильтров
+	var req SearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if req.Page < 0 || req.Limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page and limit must not be negative"})
+		return
+	}
+
+	emailStr, ok := middleware.UserEmail(c)
+	if !ok {
+		return
+	}
+
+	res, err := p.service.SearchMatches(c, emailStr, req)
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search matches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
 
-// Хэндлер регистрации действия пользователя (лайк/скип/жалоба)
-func (p *MatchHandler) RegisterActionHandler(c *gin.Context) {}
+// Хэндлер регистрации действия пользователя (лайк/скип/жалоба) над профилем кандидата
+func (p *MatchHandler) RegisterActionHandler(c *gin.Context) {
+	var req ActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	emailStr, ok := middleware.UserEmail(c)
+	if !ok {
+		return
+	}
+
+	res, err := p.service.RegisterAction(c, emailStr, c.Param("id"), req.Action)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidAction):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrProfileNotFound), errors.Is(err, ErrTargetNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrActionConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register action"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
 
 // Хэндлер получения списка совпадений, где статус = accepted
-func (p *MatchHandler) GetAcceptedMatchesHandler(c *gin.Context) {}
+func (p *MatchHandler) GetAcceptedMatchesHandler(c *gin.Context) {
+	emailStr, ok := middleware.UserEmail(c)
+	if !ok {
+		return
+	}
+
+	matches, err := p.service.GetAcceptedMatches(c, emailStr)
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get accepted matches"})
+		return
+	}
+
+	if matches == nil {
+		matches = []Candidate{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"matches": matches})
+}
 
 // Хэндлер удаления мовпадения по ID
 func (p *MatchHandler) DeleteMetchByIdHandler(c *gin.Context) {}
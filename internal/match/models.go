@@ -1,6 +1,9 @@
 package match
 
-import "time"
+import (
+	"simple_gin_server/internal/profile"
+	"time"
+)
 
 type Match struct {
 	ID                   string    `json:"id"`
@@ -19,3 +22,59 @@ type MatchAction struct {
 	CreatedBy string    `json:"created_by"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// Статусы совпадения
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusRejected = "rejected"
+)
+
+// Действия пользователя над совпадением
+const (
+	ActionLike   = "like"
+	ActionSkip   = "skip"
+	ActionReport = "report"
+)
+
+// Параметры пагинации поиска
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 50
+)
+
+// Критерии поиска совпадений (входящие данные JSON для POST /matches/search)
+type SearchRequest struct {
+	Gender   string   `json:"gender"`    // "male", "female"
+	AgeGroup string   `json:"age_group"` // "18-20", "21-25"
+	City     string   `json:"city"`      // "Москва"
+	Goal     string   `json:"goal"`      // "dating", "friendship"
+	Smoking  string   `json:"smoking"`   // "none", "sometimes"
+	Hobbies  []string `json:"hobbies"`   // хотя бы одно совпадающее хобби
+	Page     int      `json:"page"`      // номер страницы, начиная с 1
+	Limit    int      `json:"limit"`     // размер страницы, не больше MaxSearchLimit
+}
+
+// Кандидат в совпадения: данные совпадения + профиль найденного пользователя
+type Candidate struct {
+	Match
+	Profile profile.Profile `json:"profile"`
+}
+
+// Ответ на поиск совпадений
+type SearchResponse struct {
+	Matches []Candidate `json:"matches"`
+	Page    int         `json:"page"`
+	Limit   int         `json:"limit"`
+}
+
+// Входящие данные для POST /profiles/:id/actions (id - профиль кандидата)
+type ActionRequest struct {
+	Action string `json:"action" binding:"required"` // "like", "skip", "report"
+}
+
+// Ответ на регистрацию действия: действие и состояние совпадения после него
+type ActionResponse struct {
+	Action MatchAction `json:"action"`
+	Match  Match       `json:"match"`
+}
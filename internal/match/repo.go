@@ -1,17 +1,359 @@
 package match
 
-import "simple_gin_server/pkg/db"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"simple_gin_server/internal/profile"
+	"simple_gin_server/pkg/db"
+	"strings"
 
-// Интерфейс для слоя productsSRepository для использования другими источниками
-type MatchRepoInterface interface{}
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+)
+
+// Интерфейс для слоя matchRepository для использования другими источниками
+type MatchRepoInterface interface {
+	GetProfileByEmail(ctx context.Context, email string) (*profile.Profile, error)
+	SearchProfiles(ctx context.Context, ownProfileId string, filter SearchRequest, limit, offset int) ([]profile.Profile, error)
+	GetProfileByID(ctx context.Context, profileId string) (*profile.Profile, error)
+	ApplyAction(ctx context.Context, profileId, targetId string, compatibility int, action string) (*MatchAction, *Match, bool, error)
+	GetAcceptedMatches(ctx context.Context, profileId string) ([]Candidate, error)
+}
 
 type MatchRepository struct {
 	Database db.PgRepoInterface
+	log      *slog.Logger
 }
 
 // Конструктор репозитория
-func NewMatchRepository(dataBase db.PgRepoInterface) *MatchRepository {
+func NewMatchRepository(dataBase db.PgRepoInterface, log *slog.Logger) *MatchRepository {
 	return &MatchRepository{
 		Database: dataBase,
+		log:      log,
+	}
+}
+
+// списки колонок совпадения и действия
+const (
+	matchColumns  = `id::text, initiator_id::text, target_id::text, compatibility, status, created_at, updated_at`
+	actionColumns = `id::text, match_id::text, action, created_by::text, created_at`
+)
+
+// список колонок профиля, общий для всех выборок из таблицы profiles
+const profileColumns = `p.id::text, p.user_id::text, p.name, p.nick_name, p.gender, p.age_group, p.city,
+	p.profession, p.smoking, p.goal, p.hobbies, p.social_link, p.rating, p.created_at, p.updated_at`
+
+// Получение профиля пользователя по его Email
+func (r *MatchRepository) GetProfileByEmail(ctx context.Context, email string) (*profile.Profile, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + profileColumns + `
+		FROM profiles p
+		JOIN users u ON u.id = p.user_id
+		WHERE u.email = $1
+		LIMIT 1
+	`
+
+	prof, err := scanProfile(r.Database.GetPool().QueryRow(ctx, query, email))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to query profile by email: %w", err)
+	}
+
+	return prof, nil
+}
+
+// Получение профиля по его ID
+func (r *MatchRepository) GetProfileByID(ctx context.Context, profileId string) (*profile.Profile, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + profileColumns + ` FROM profiles p WHERE p.id = $1::uuid`
+
+	prof, err := scanProfile(r.Database.GetPool().QueryRow(ctx, query, profileId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTargetNotFound
+		}
+		return nil, fmt.Errorf("failed to query profile by id: %w", err)
+	}
+
+	return prof, nil
+}
+
+// Поиск профилей по критериям фильтра. Из выборки исключаются собственный профиль,
+// профили, по которым пользователь уже зарегистрировал действие (лайк/скип/жалоба),
+// и профили, совпадение с которыми уже принято или отклонено
+func (r *MatchRepository) SearchProfiles(ctx context.Context, ownProfileId string, filter SearchRequest, limit, offset int) ([]profile.Profile, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	conditions := []string{
+		`p.id <> $1::uuid`,
+		`NOT EXISTS (
+			SELECT 1
+			FROM matches m
+			WHERE ((m.initiator_id = $1 AND m.target_id = p.id) OR (m.initiator_id = p.id AND m.target_id = $1))
+				AND (m.status <> 'pending' OR EXISTS (
+					SELECT 1 FROM match_actions a WHERE a.match_id = m.id AND a.created_by = $1
+				))
+		)`,
+	}
+	args := []interface{}{ownProfileId}
+
+	// добавляет условие с очередным позиционным параметром
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.Gender != "" {
+		addCondition("p.gender = $%d", filter.Gender)
+	}
+	if filter.AgeGroup != "" {
+		addCondition("p.age_group = $%d", filter.AgeGroup)
+	}
+	if filter.City != "" {
+		addCondition("p.city = $%d", filter.City)
+	}
+	if filter.Goal != "" {
+		addCondition("p.goal = $%d", filter.Goal)
+	}
+	if filter.Smoking != "" {
+		addCondition("p.smoking = $%d", filter.Smoking)
+	}
+	if len(filter.Hobbies) > 0 {
+		addCondition("p.hobbies && $%d", filter.Hobbies) // пересечение массивов
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM profiles p
+		WHERE %s
+		ORDER BY p.rating DESC, p.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, profileColumns, strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	rows, err := r.Database.GetPool().Query(ctx, query, args...)
+	if err != nil {
+		r.log.ErrorContext(ctx, "failed to search profiles", "error", err)
+		return nil, fmt.Errorf("failed to search profiles: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []profile.Profile
+	for rows.Next() {
+		prof, err := scanProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan profile: %w", err)
+		}
+		profiles = append(profiles, *prof)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// Регистрирует действие профиля profileId над профилем targetId и пересчитывает статус совпадения.
+// Совпадение пары создаётся при первом действии любой из сторон (инициатор - тот, кто действует первым),
+// поиск строк в matches не создаёт. Выполняется в транзакции с блокировкой строки совпадения, чтобы
+// одновременные лайки обеих сторон не потеряли переход в accepted. Повторное действие не создаёт
+// новую запись: возвращается ранее сохранённое действие и created = false
+func (r *MatchRepository) ApplyAction(ctx context.Context, profileId, targetId string, compatibility int, action string) (*MatchAction, *Match, bool, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, nil, false, err
+	}
+
+	tx, err := r.Database.GetPool().Begin(ctx)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// для пары профилей существует только одно совпадение, в том числе созданное второй стороной
+	_, err = tx.Exec(ctx, `
+		INSERT INTO matches (id, initiator_id, target_id, compatibility, status)
+		VALUES ($1, $2, $3, $4, '`+StatusPending+`')
+		ON CONFLICT ((LEAST(initiator_id, target_id)), (GREATEST(initiator_id, target_id))) DO NOTHING
+	`, uuid.New().String(), profileId, targetId, compatibility)
+	if err != nil {
+		r.log.ErrorContext(ctx, "failed to create match", "error", err)
+		return nil, nil, false, fmt.Errorf("failed to create match: %w", err)
+	}
+
+	// блокируем совпадение до пересчёта статуса
+	m, err := scanMatch(tx.QueryRow(ctx, `
+		SELECT `+matchColumns+`
+		FROM matches
+		WHERE LEAST(initiator_id, target_id) = LEAST($1::uuid, $2::uuid)
+			AND GREATEST(initiator_id, target_id) = GREATEST($1::uuid, $2::uuid)
+		FOR UPDATE
+	`, profileId, targetId))
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to query match: %w", err)
+	}
+
+	var act MatchAction
+	created := true
+	err = tx.QueryRow(ctx, `
+		INSERT INTO match_actions (id, match_id, action, created_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (match_id, created_by) DO NOTHING
+		RETURNING `+actionColumns,
+		uuid.New().String(), m.ID, action, profileId,
+	).Scan(&act.ID, &act.MatchID, &act.Action, &act.CreatedBy, &act.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// действие уже было зарегистрировано ранее
+		created = false
+		err = tx.QueryRow(ctx, `
+			SELECT `+actionColumns+`
+			FROM match_actions
+			WHERE match_id = $1::uuid AND created_by = $2::uuid
+		`, m.ID, profileId).Scan(&act.ID, &act.MatchID, &act.Action, &act.CreatedBy, &act.CreatedAt)
+	}
+	if err != nil {
+		r.log.ErrorContext(ctx, "failed to save match action", "error", err)
+		return nil, nil, false, fmt.Errorf("failed to save match action: %w", err)
+	}
+
+	// все действия по совпадению для пересчёта статуса
+	rows, err := tx.Query(ctx, `SELECT `+actionColumns+` FROM match_actions WHERE match_id = $1::uuid`, m.ID)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to query match actions: %w", err)
+	}
+	var actions []MatchAction
+	for rows.Next() {
+		var a MatchAction
+		if err := rows.Scan(&a.ID, &a.MatchID, &a.Action, &a.CreatedBy, &a.CreatedAt); err != nil {
+			rows.Close()
+			return nil, nil, false, fmt.Errorf("failed to scan match action: %w", err)
+		}
+		actions = append(actions, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, false, fmt.Errorf("error after iterating rows: %w", err)
+	}
+
+	if status := resolveStatus(m, actions); status != m.Status {
+		err = tx.QueryRow(ctx, `
+			UPDATE matches SET status = $2, updated_at = NOW()
+			WHERE id = $1::uuid
+			RETURNING updated_at
+		`, m.ID, status).Scan(&m.UpdatedAt)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to update match status: %w", err)
+		}
+		m.Status = status
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, false, fmt.Errorf("failed to commit match action: %w", err)
+	}
+
+	return &act, m, created, nil
+}
+
+// Получение принятых совпадений профиля вместе с профилем второй стороны
+func (r *MatchRepository) GetAcceptedMatches(ctx context.Context, profileId string) ([]Candidate, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + matchColumns + `, ` + profileColumns + `
+		FROM matches
+		JOIN profiles p ON p.id = CASE WHEN initiator_id = $1::uuid THEN target_id ELSE initiator_id END
+		WHERE (initiator_id = $1::uuid OR target_id = $1::uuid) AND status = '` + StatusAccepted + `'
+		ORDER BY updated_at DESC
+	`
+
+	rows, err := r.Database.GetPool().Query(ctx, query, profileId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query accepted matches: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []Candidate
+	for rows.Next() {
+		var c Candidate
+		err := rows.Scan(
+			&c.ID, &c.InitiatorID, &c.TargetID, &c.CompatibilityPercent, &c.Status, &c.CreatedAt, &c.UpdatedAt,
+			&c.Profile.ID, &c.Profile.UserID, &c.Profile.Name, &c.Profile.NickName, &c.Profile.Gender,
+			&c.Profile.AgeGroup, &c.Profile.City, &c.Profile.Profession, &c.Profile.Smoking, &c.Profile.Goal,
+			&c.Profile.Hobbies, &c.Profile.SocialLink, &c.Profile.Rating, &c.Profile.CreatedAt, &c.Profile.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan accepted match: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// вычитывает профиль из строки результата в порядке profileColumns
+func scanProfile(row pgx.Row) (*profile.Profile, error) {
+	var prof profile.Profile
+	err := row.Scan(
+		&prof.ID,
+		&prof.UserID,
+		&prof.Name,
+		&prof.NickName,
+		&prof.Gender,
+		&prof.AgeGroup,
+		&prof.City,
+		&prof.Profession,
+		&prof.Smoking,
+		&prof.Goal,
+		&prof.Hobbies,
+		&prof.SocialLink,
+		&prof.Rating,
+		&prof.CreatedAt,
+		&prof.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &prof, nil
+}
+
+// вычитывает совпадение из строки результата в порядке matchColumns
+func scanMatch(row pgx.Row) (*Match, error) {
+	var m Match
+	err := row.Scan(
+		&m.ID,
+		&m.InitiatorID,
+		&m.TargetID,
+		&m.CompatibilityPercent,
+		&m.Status,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
 	}
+	return &m, nil
 }
@@ -0,0 +1,166 @@
+package match
+
+import (
+	"log/slog"
+	"math"
+	"simple_gin_server/configs"
+	"simple_gin_server/internal/profile"
+	"strconv"
+	"strings"
+)
+
+// Алгоритм подсчёта совместимости двух профилей, результат в диапазоне 0-100
+type Scorer interface {
+	Score(a, b *profile.Profile) int
+}
+
+// Фабрика алгоритма подсчёта совместимости по весам из конфига
+type ScorerFactory func(weights configs.MatchWeights) Scorer
+
+// Зарегистрированные алгоритмы, выбираются по имени из configs.MatchConfig.Scorer
+var scorers = map[string]ScorerFactory{
+	"weighted": func(w configs.MatchWeights) Scorer { return NewWeightedScorer(w) },
+	"hobbies":  func(w configs.MatchWeights) Scorer { return NewWeightedScorer(configs.MatchWeights{Hobbies: 1}) },
+}
+
+// Регистрирует алгоритм под заданным именем (например, для A/B тестов).
+// Вызывается только из init() пакетов: реестр не защищён от конкурентного доступа.
+// Паникует при повторной регистрации имени
+func RegisterScorer(name string, factory ScorerFactory) {
+	if factory == nil {
+		panic("match: RegisterScorer factory is nil")
+	}
+	if _, dup := scorers[name]; dup {
+		panic("match: RegisterScorer called twice for scorer " + name)
+	}
+	scorers[name] = factory
+}
+
+// Создаёт алгоритм, указанный в конфиге; при неизвестном имени используется "weighted"
+func NewScorer(conf configs.MatchConfig, log *slog.Logger) Scorer {
+	factory, ok := scorers[conf.Scorer]
+	if !ok {
+		log.Warn("unknown match scorer, using \"weighted\"", "scorer", conf.Scorer)
+		factory = scorers["weighted"]
+	}
+	return factory(conf.Weights)
+}
+
+// Взвешенная сумма совпадений по полям профиля
+type WeightedScorer struct {
+	weights configs.MatchWeights
+}
+
+// Конструктор взвешенного алгоритма
+func NewWeightedScorer(weights configs.MatchWeights) *WeightedScorer {
+	return &WeightedScorer{
+		weights: weights,
+	}
+}
+
+func (s *WeightedScorer) Score(a, b *profile.Profile) int {
+	w := s.weights
+	total := w.Hobbies + w.City + w.Goal + w.Smoking + w.AgeGroup
+	if total <= 0 {
+		return 0
+	}
+
+	sum := w.Hobbies*jaccard(a.Hobbies, b.Hobbies) +
+		w.City*equalNonEmpty(a.City, b.City) +
+		w.Goal*equalNonEmpty(a.Goal, b.Goal) +
+		w.Smoking*smokingSimilarity(a.Smoking, b.Smoking) +
+		w.AgeGroup*ageGroupSimilarity(a.AgeGroup, b.AgeGroup)
+
+	// отрицательные веса отсекаются при проверке конфига, но алгоритм может быть создан и напрямую:
+	// результат всегда в диапазоне 0-100 (CHECK на matches.compatibility)
+	return int(math.Round(100 * min(max(sum/total, 0), 1)))
+}
+
+// коэффициент Жаккара: |A ∩ B| / |A ∪ B|
+func jaccard(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, h := range a {
+		set[strings.ToLower(h)] = false
+	}
+
+	union := len(set)
+	common := 0
+	for _, h := range b {
+		h = strings.ToLower(h)
+		seen, ok := set[h]
+		switch {
+		case !ok:
+			set[h] = true
+			union++
+		case !seen:
+			set[h] = true
+			common++
+		}
+	}
+
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// 1 при совпадении непустых значений, иначе 0
+func equalNonEmpty(a, b string) float64 {
+	if a != "" && strings.EqualFold(a, b) {
+		return 1
+	}
+	return 0
+}
+
+// Степени курения по возрастанию, соседние значения частично совместимы
+var smokingLevels = map[string]int{
+	"none":      0,
+	"sometimes": 1,
+	"regularly": 2,
+}
+
+func smokingSimilarity(a, b string) float64 {
+	la, okA := smokingLevels[a]
+	lb, okB := smokingLevels[b]
+	if !okA || !okB {
+		return equalNonEmpty(a, b)
+	}
+	return 1 - math.Abs(float64(la-lb))/float64(len(smokingLevels)-1)
+}
+
+// 1 для одинаковых возрастных групп, 0.5 для соседних ("18-20" и "21-25"), иначе 0
+func ageGroupSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	loA, hiA, okA := parseAgeGroup(a)
+	loB, hiB, okB := parseAgeGroup(b)
+	if !okA || !okB {
+		return 0
+	}
+	if loB == hiA+1 || loA == hiB+1 {
+		return 0.5
+	}
+	return 0
+}
+
+// разбирает возрастную группу вида "21-25"
+func parseAgeGroup(group string) (int, int, bool) {
+	loStr, hiStr, found := strings.Cut(group, "-")
+	if !found {
+		return 0, 0, false
+	}
+	lo, err := strconv.Atoi(strings.TrimSpace(loStr))
+	if err != nil {
+		return 0, 0, false
+	}
+	hi, err := strconv.Atoi(strings.TrimSpace(hiStr))
+	if err != nil {
+		return 0, 0, false
+	}
+	return lo, hi, true
+}
@@ -0,0 +1,90 @@
+package match
+
+import (
+	"simple_gin_server/configs"
+	"simple_gin_server/internal/profile"
+	"simple_gin_server/pkg/logger"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// тест взвешенного алгоритма совместимости
+func TestWeightedScorer_Score(t *testing.T) {
+	weights := configs.MatchWeights{Hobbies: 0.4, City: 0.2, Goal: 0.2, Smoking: 0.1, AgeGroup: 0.1}
+
+	tests := []struct {
+		name string
+		a, b profile.Profile
+		want int
+	}{
+		{
+			name: "identical profiles",
+			a:    profile.Profile{City: "Москва", Goal: "dating", Smoking: "none", AgeGroup: "21-25", Hobbies: []string{"music"}},
+			b:    profile.Profile{City: "Москва", Goal: "dating", Smoking: "none", AgeGroup: "21-25", Hobbies: []string{"music"}},
+			want: 100,
+		},
+		{
+			name: "nothing in common",
+			a:    profile.Profile{City: "Москва", Goal: "dating", Smoking: "none", AgeGroup: "18-20", Hobbies: []string{"music"}},
+			b:    profile.Profile{City: "Казань", Goal: "friendship", Smoking: "regularly", AgeGroup: "26-30", Hobbies: []string{"travel"}},
+			want: 0,
+		},
+		{
+			name: "partial hobbies, adjacent age groups, close smoking habits",
+			a:    profile.Profile{Smoking: "none", AgeGroup: "18-20", Hobbies: []string{"music", "travel"}},
+			b:    profile.Profile{Smoking: "sometimes", AgeGroup: "21-25", Hobbies: []string{"Travel", "books", "sport"}},
+			// hobbies 1/4*0.4 + smoking 0.5*0.1 + age 0.5*0.1 = 0.2
+			want: 20,
+		},
+		{
+			name: "empty profiles",
+			want: 0,
+		},
+	}
+
+	scorer := NewWeightedScorer(weights)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, scorer.Score(&tt.a, &tt.b))
+			assert.Equal(t, tt.want, scorer.Score(&tt.b, &tt.a))
+		})
+	}
+}
+
+// тест выбора алгоритма по имени из конфига
+func TestNewScorer(t *testing.T) {
+	a := &profile.Profile{City: "Москва", Hobbies: []string{"music"}}
+	b := &profile.Profile{City: "Казань", Hobbies: []string{"music"}}
+	weights := configs.MatchWeights{Hobbies: 1, City: 1}
+
+	assert.Equal(t, 50, NewScorer(configs.MatchConfig{Scorer: "weighted", Weights: weights}, logger.Nop()).Score(a, b))
+	assert.Equal(t, 100, NewScorer(configs.MatchConfig{Scorer: "hobbies", Weights: weights}, logger.Nop()).Score(a, b))
+	assert.Equal(t, 50, NewScorer(configs.MatchConfig{Scorer: "unknown", Weights: weights}, logger.Nop()).Score(a, b))
+}
+
+// тест границ результата: при весах разного знака результат не выходит за 0-100
+func TestWeightedScorer_Score_Clamped(t *testing.T) {
+	scorer := NewWeightedScorer(configs.MatchWeights{Hobbies: 1, City: -0.5})
+
+	// hobbies 1*1 / total 0.5 = 200%
+	assert.Equal(t, 100, scorer.Score(
+		&profile.Profile{City: "Москва", Hobbies: []string{"music"}},
+		&profile.Profile{City: "Казань", Hobbies: []string{"music"}},
+	))
+	// city -0.5*1 / total 0.5 = -100%
+	assert.Equal(t, 0, scorer.Score(
+		&profile.Profile{City: "Москва", Hobbies: []string{"music"}},
+		&profile.Profile{City: "Москва", Hobbies: []string{"travel"}},
+	))
+}
+
+// тест регистрации алгоритма: повторное имя - ошибка программиста
+func TestRegisterScorer(t *testing.T) {
+	RegisterScorer("test-constant", func(configs.MatchWeights) Scorer { return NewWeightedScorer(configs.MatchWeights{}) })
+	t.Cleanup(func() { delete(scorers, "test-constant") })
+
+	assert.Equal(t, 0, NewScorer(configs.MatchConfig{Scorer: "test-constant"}, logger.Nop()).Score(&profile.Profile{}, &profile.Profile{}))
+	assert.Panics(t, func() { RegisterScorer("weighted", scorers["weighted"]) })
+	assert.Panics(t, func() { RegisterScorer("nil-factory", nil) })
+}
@@ -1,15 +1,156 @@
 package match
 
-// Интерфейс для слоя productsService для использования другими источниками
-type ServiceInterface interface{}
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Интерфейс для слоя matchService для использования другими источниками
+type ServiceInterface interface {
+	SearchMatches(ctx context.Context, email string, req SearchRequest) (*SearchResponse, error)
+	RegisterAction(ctx context.Context, email, targetId, action string) (*ActionResponse, error)
+	GetAcceptedMatches(ctx context.Context, email string) ([]Candidate, error)
+}
 
 type MatchService struct {
-	repo MatchRepoInterface
+	repo   MatchRepoInterface
+	scorer Scorer
 }
 
 // Конструктор слоя сервис
-func NewMatchService(repo MatchRepoInterface) *MatchService {
+func NewMatchService(repo MatchRepoInterface, scorer Scorer) *MatchService {
 	return &MatchService{
-		repo: repo,
+		repo:   repo,
+		scorer: scorer,
+	}
+}
+
+// Поиск кандидатов в совпадения для пользователя с заданным Email по критериям запроса
+func (s *MatchService) SearchMatches(ctx context.Context, email string, req SearchRequest) (*SearchResponse, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// нормализуем параметры пагинации
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = DefaultSearchLimit
+	}
+	if req.Limit > MaxSearchLimit {
+		req.Limit = MaxSearchLimit
+	}
+
+	// профиль того, кто ищет
+	own, err := s.repo.GetProfileByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := s.repo.SearchProfiles(ctx, own.ID, req, req.Limit, (req.Page-1)*req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(profiles))
+	for _, target := range profiles {
+		candidates = append(candidates, Candidate{
+			Match: Match{
+				InitiatorID:          own.ID,
+				TargetID:             target.ID,
+				CompatibilityPercent: s.scorer.Score(own, &target),
+				Status:               StatusPending,
+			},
+			Profile: target,
+		})
+	}
+
+	return &SearchResponse{
+		Matches: candidates,
+		Page:    req.Page,
+		Limit:   req.Limit,
+	}, nil
+}
+
+// Регистрация действия (лайк/скип/жалоба) пользователя с заданным Email над профилем кандидата targetId
+// (id профиля из результатов поиска). Повтор того же действия идемпотентен, попытка сменить уже
+// зарегистрированное действие - ошибка
+func (s *MatchService) RegisterAction(ctx context.Context, email, targetId, action string) (*ActionResponse, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if action != ActionLike && action != ActionSkip && action != ActionReport {
+		return nil, ErrInvalidAction
+	}
+
+	if _, err := uuid.Parse(targetId); err != nil {
+		return nil, ErrTargetNotFound
+	}
+
+	own, err := s.repo.GetProfileByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if own.ID == targetId {
+		return nil, ErrTargetNotFound
+	}
+
+	target, err := s.repo.GetProfileByID(ctx, targetId)
+	if err != nil {
+		return nil, err
+	}
+
+	// совместимость сохраняется в совпадении, если оно создаётся этим действием
+	act, m, created, err := s.repo.ApplyAction(ctx, own.ID, target.ID, s.scorer.Score(own, target), action)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created && act.Action != action {
+		return nil, ErrActionConflict
+	}
+
+	return &ActionResponse{
+		Action: *act,
+		Match:  *m,
+	}, nil
+}
+
+// Получение принятых обеими сторонами совпадений пользователя с заданным Email
+func (s *MatchService) GetAcceptedMatches(ctx context.Context, email string) ([]Candidate, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	own, err := s.repo.GetProfileByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAcceptedMatches(ctx, own.ID)
+}
+
+// Статус совпадения по зарегистрированным действиям: скип или жалоба любой стороны
+// отклоняют совпадение, взаимный лайк принимает его, иначе совпадение остаётся pending
+func resolveStatus(m *Match, actions []MatchAction) string {
+	liked := make(map[string]bool, 2)
+	for _, a := range actions {
+		switch a.Action {
+		case ActionSkip, ActionReport:
+			return StatusRejected
+		case ActionLike:
+			liked[a.CreatedBy] = true
+		}
+	}
+
+	if liked[m.InitiatorID] && liked[m.TargetID] {
+		return StatusAccepted
 	}
+	return StatusPending
 }
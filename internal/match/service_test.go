@@ -0,0 +1,224 @@
+package match
+
+import (
+	"context"
+	"simple_gin_server/configs"
+	"simple_gin_server/internal/profile"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// мок репозитория совпадений (лежит здесь, т.к. moks не может импортировать match без цикла)
+type mockMatchRepo struct {
+	mock.Mock
+}
+
+func (m *mockMatchRepo) GetProfileByEmail(ctx context.Context, email string) (*profile.Profile, error) {
+	args := m.Called(ctx, email)
+	prof, _ := args.Get(0).(*profile.Profile)
+	return prof, args.Error(1)
+}
+
+func (m *mockMatchRepo) SearchProfiles(ctx context.Context, ownProfileId string, filter SearchRequest, limit, offset int) ([]profile.Profile, error) {
+	args := m.Called(ctx, ownProfileId, filter, limit, offset)
+	return args.Get(0).([]profile.Profile), args.Error(1)
+}
+
+func (m *mockMatchRepo) GetProfileByID(ctx context.Context, profileId string) (*profile.Profile, error) {
+	args := m.Called(ctx, profileId)
+	prof, _ := args.Get(0).(*profile.Profile)
+	return prof, args.Error(1)
+}
+
+func (m *mockMatchRepo) ApplyAction(ctx context.Context, profileId, targetId string, compatibility int, action string) (*MatchAction, *Match, bool, error) {
+	args := m.Called(ctx, profileId, targetId, compatibility, action)
+	act, _ := args.Get(0).(*MatchAction)
+	match, _ := args.Get(1).(*Match)
+	return act, match, args.Bool(2), args.Error(3)
+}
+
+func (m *mockMatchRepo) GetAcceptedMatches(ctx context.Context, profileId string) ([]Candidate, error) {
+	args := m.Called(ctx, profileId)
+	return args.Get(0).([]Candidate), args.Error(1)
+}
+
+// тест для метода SearchMatches у слоя Service
+func TestMatchService_SearchMatches(t *testing.T) {
+	own := &profile.Profile{ID: "own", City: "Москва", Goal: "dating", Hobbies: []string{"music", "travel"}}
+
+	t.Run("pagination and compatibility", func(t *testing.T) {
+		repo := new(mockMatchRepo)
+		service := NewMatchService(repo, NewWeightedScorer(configs.MatchWeights{Hobbies: 1, City: 1, Goal: 1}))
+
+		req := SearchRequest{City: "Москва", Page: 3, Limit: 100}
+		found := []profile.Profile{
+			{ID: "p1", City: "Москва", Goal: "dating", Hobbies: []string{"music", "travel"}},
+			{ID: "p2", City: "Казань", Goal: "friendship"},
+		}
+
+		repo.On("GetProfileByEmail", mock.Anything, "test@example.com").Return(own, nil)
+		repo.On("SearchProfiles", mock.Anything, "own", mock.Anything, MaxSearchLimit, 2*MaxSearchLimit).Return(found, nil)
+
+		res, err := service.SearchMatches(context.Background(), "test@example.com", req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, res.Page)
+		assert.Equal(t, MaxSearchLimit, res.Limit)
+		assert.Len(t, res.Matches, 2)
+		assert.Equal(t, 100, res.Matches[0].CompatibilityPercent)
+		assert.Equal(t, 0, res.Matches[1].CompatibilityPercent)
+		assert.Equal(t, "own", res.Matches[0].InitiatorID)
+		assert.Equal(t, "p1", res.Matches[0].TargetID)
+		assert.Equal(t, "p1", res.Matches[0].Profile.ID)
+		assert.Equal(t, StatusPending, res.Matches[0].Status)
+		repo.AssertExpectations(t) // поиск только читает, совпадения не создаются
+	})
+
+	t.Run("caller has no profile", func(t *testing.T) {
+		repo := new(mockMatchRepo)
+		service := NewMatchService(repo, NewWeightedScorer(configs.MatchWeights{Hobbies: 1, City: 1, Goal: 1}))
+
+		repo.On("GetProfileByEmail", mock.Anything, "test@example.com").Return(nil, ErrProfileNotFound)
+
+		_, err := service.SearchMatches(context.Background(), "test@example.com", SearchRequest{})
+
+		assert.ErrorIs(t, err, ErrProfileNotFound)
+		repo.AssertNotCalled(t, "SearchProfiles")
+	})
+}
+
+// тест для метода RegisterAction у слоя Service
+func TestMatchService_RegisterAction(t *testing.T) {
+	own := &profile.Profile{ID: "own", City: "Москва"}
+	targetId := "0b7f2f63-3a48-4c4c-9c4f-6a1d2f8a9e11"
+	target := &profile.Profile{ID: targetId, City: "Москва"}
+	matchId := "5c1d7a0e-2f4b-4e8e-9d6a-3b2c1f0e9a87"
+
+	// сервис с весом только по городу: совместимость own и target - 100%
+	setUp := func() (*mockMatchRepo, *MatchService) {
+		repo := new(mockMatchRepo)
+		return repo, NewMatchService(repo, NewWeightedScorer(configs.MatchWeights{City: 1}))
+	}
+
+	t.Run("first like creates match", func(t *testing.T) {
+		repo, service := setUp()
+
+		repo.On("GetProfileByEmail", mock.Anything, "test@example.com").Return(own, nil)
+		repo.On("GetProfileByID", mock.Anything, targetId).Return(target, nil)
+		repo.On("ApplyAction", mock.Anything, "own", targetId, 100, ActionLike).Return(
+			&MatchAction{MatchID: matchId, Action: ActionLike, CreatedBy: "own"},
+			&Match{ID: matchId, InitiatorID: "own", TargetID: targetId, CompatibilityPercent: 100, Status: StatusPending},
+			true, nil,
+		)
+
+		res, err := service.RegisterAction(context.Background(), "test@example.com", targetId, ActionLike)
+
+		assert.NoError(t, err)
+		assert.Equal(t, matchId, res.Match.ID)
+		assert.Equal(t, StatusPending, res.Match.Status)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("mutual like", func(t *testing.T) {
+		repo, service := setUp()
+
+		repo.On("GetProfileByEmail", mock.Anything, "test@example.com").Return(own, nil)
+		repo.On("GetProfileByID", mock.Anything, targetId).Return(target, nil)
+		repo.On("ApplyAction", mock.Anything, "own", targetId, 100, ActionLike).Return(
+			&MatchAction{MatchID: matchId, Action: ActionLike, CreatedBy: "own"},
+			&Match{ID: matchId, InitiatorID: targetId, TargetID: "own", Status: StatusAccepted},
+			true, nil,
+		)
+
+		res, err := service.RegisterAction(context.Background(), "test@example.com", targetId, ActionLike)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusAccepted, res.Match.Status)
+	})
+
+	t.Run("repeated action is idempotent", func(t *testing.T) {
+		repo, service := setUp()
+
+		repo.On("GetProfileByEmail", mock.Anything, "test@example.com").Return(own, nil)
+		repo.On("GetProfileByID", mock.Anything, targetId).Return(target, nil)
+		repo.On("ApplyAction", mock.Anything, "own", targetId, 100, ActionSkip).Return(
+			&MatchAction{MatchID: matchId, Action: ActionSkip, CreatedBy: "own"},
+			&Match{ID: matchId, Status: StatusRejected},
+			false, nil,
+		)
+
+		res, err := service.RegisterAction(context.Background(), "test@example.com", targetId, ActionSkip)
+
+		assert.NoError(t, err)
+		assert.Equal(t, StatusRejected, res.Match.Status)
+	})
+
+	t.Run("changing registered action", func(t *testing.T) {
+		repo, service := setUp()
+
+		repo.On("GetProfileByEmail", mock.Anything, "test@example.com").Return(own, nil)
+		repo.On("GetProfileByID", mock.Anything, targetId).Return(target, nil)
+		repo.On("ApplyAction", mock.Anything, "own", targetId, 100, ActionLike).Return(
+			&MatchAction{MatchID: matchId, Action: ActionSkip, CreatedBy: "own"},
+			&Match{ID: matchId, Status: StatusRejected},
+			false, nil,
+		)
+
+		_, err := service.RegisterAction(context.Background(), "test@example.com", targetId, ActionLike)
+
+		assert.ErrorIs(t, err, ErrActionConflict)
+	})
+
+	t.Run("unknown target", func(t *testing.T) {
+		repo, service := setUp()
+
+		repo.On("GetProfileByEmail", mock.Anything, "test@example.com").Return(own, nil)
+		repo.On("GetProfileByID", mock.Anything, targetId).Return(nil, ErrTargetNotFound)
+
+		_, err := service.RegisterAction(context.Background(), "test@example.com", targetId, ActionLike)
+
+		assert.ErrorIs(t, err, ErrTargetNotFound)
+		repo.AssertNotCalled(t, "ApplyAction")
+	})
+
+	t.Run("invalid action, target id and own profile", func(t *testing.T) {
+		repo, service := setUp()
+		repo.On("GetProfileByEmail", mock.Anything, "test@example.com").Return(&profile.Profile{ID: targetId}, nil)
+
+		_, err := service.RegisterAction(context.Background(), "test@example.com", targetId, "poke")
+		assert.ErrorIs(t, err, ErrInvalidAction)
+
+		_, err = service.RegisterAction(context.Background(), "test@example.com", "not-a-uuid", ActionLike)
+		assert.ErrorIs(t, err, ErrTargetNotFound)
+
+		_, err = service.RegisterAction(context.Background(), "test@example.com", targetId, ActionLike)
+		assert.ErrorIs(t, err, ErrTargetNotFound)
+
+		repo.AssertNotCalled(t, "ApplyAction")
+	})
+}
+
+// тест пересчёта статуса совпадения по действиям сторон
+func TestResolveStatus(t *testing.T) {
+	m := &Match{InitiatorID: "a", TargetID: "b", Status: StatusPending}
+
+	tests := []struct {
+		name    string
+		actions []MatchAction
+		want    string
+	}{
+		{name: "no actions", want: StatusPending},
+		{name: "one like", actions: []MatchAction{{Action: ActionLike, CreatedBy: "a"}}, want: StatusPending},
+		{name: "mutual like", actions: []MatchAction{{Action: ActionLike, CreatedBy: "a"}, {Action: ActionLike, CreatedBy: "b"}}, want: StatusAccepted},
+		{name: "like and skip", actions: []MatchAction{{Action: ActionLike, CreatedBy: "a"}, {Action: ActionSkip, CreatedBy: "b"}}, want: StatusRejected},
+		{name: "report", actions: []MatchAction{{Action: ActionReport, CreatedBy: "b"}}, want: StatusRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolveStatus(m, tt.actions))
+		})
+	}
+}
@@ -2,8 +2,10 @@ package moks
 
 import (
 	"context"
+	"simple_gin_server/internal/sessions"
 	"simple_gin_server/internal/users"
 	"simple_gin_server/pkg/jwt_stuff"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -17,8 +19,8 @@ func (m *MockAuthService) Register(ctx context.Context, email, password string)
 	return args.Error(0) // Возвращаем error (может быть nil)
 }
 
-func (m *MockAuthService) Login(ctx context.Context, email, password string) error {
-	args := m.Called(ctx, email, password)
+func (m *MockAuthService) Login(ctx context.Context, email, password, ip string) error {
+	args := m.Called(ctx, email, password, ip)
 	return args.Error(0) // Возвращаем error (может быть nil)
 }
 
@@ -27,13 +29,13 @@ func (m *MockAuthService) GetUserList(ctx context.Context) ([]string, error) {
 	return args.Get(0).([]string), args.Error(1) // Возвращаем слайс и error
 }
 
-func (m *MockAuthService) AddRefreshTokenToDb(ctx context.Context, email, refreshToken string) error {
-	args := m.Called(ctx, email, refreshToken)
+func (m *MockAuthService) CreateSession(ctx context.Context, userId int, refreshToken string, client sessions.ClientInfo) error {
+	args := m.Called(ctx, userId, refreshToken, client)
 	return args.Error(0) // Возвращаем error (может быть nil)
 }
 
-func (m *MockAuthService) InvalidateRefreshToken(ctx context.Context, refreshToken string) error {
-	args := m.Called(ctx, refreshToken)
+func (m *MockAuthService) InvalidateRefreshToken(ctx context.Context, userId int, claims jwt_stuff.CustomClaims) error {
+	args := m.Called(ctx, userId, claims.ID)
 	return args.Error(0) // Возвращаем error (может быть nil)
 }
 
@@ -51,3 +53,75 @@ func (m *MockAuthService) GetUserByEmail(ctx context.Context, email string) (*us
 	args := m.Called(ctx)
 	return args.Get(0).(*users.User), args.Error(1) // Возвращаем слайс и error
 }
+
+func (m *MockAuthService) DeleteUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0) // Возвращаем error (может быть nil)
+}
+
+func (m *MockAuthService) ValidateRefreshToken(ctx context.Context, claims jwt_stuff.CustomClaims) (*users.User, error) {
+	args := m.Called(ctx, claims)
+	user, _ := args.Get(0).(*users.User)
+	return user, args.Error(1)
+}
+
+func (m *MockAuthService) RotateRefreshToken(ctx context.Context, claims jwt_stuff.CustomClaims, newToken string) error {
+	args := m.Called(ctx, claims, newToken)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ListSessions(ctx context.Context, userId int) ([]sessions.Session, error) {
+	args := m.Called(ctx, userId)
+	list, _ := args.Get(0).([]sessions.Session)
+	return list, args.Error(1)
+}
+
+func (m *MockAuthService) RevokeSession(ctx context.Context, userId int, sessionId string) error {
+	args := m.Called(ctx, userId, sessionId)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) LogoutAll(ctx context.Context, userId int) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) TokenState(ctx context.Context, userId int) (int, bool, error) {
+	args := m.Called(ctx, userId)
+	return args.Int(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockAuthService) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ForgotPassword(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	args := m.Called(ctx, token, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ChangePassword(ctx context.Context, userId int, sessionId, currentPassword, newPassword, ip string) (string, string, error) {
+	args := m.Called(ctx, userId, sessionId, currentPassword, newPassword, ip)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockAuthService) UnlockAccount(ctx context.Context, userId int) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
@@ -0,0 +1,17 @@
+package moks
+
+import (
+	"context"
+	"simple_gin_server/pkg/mailer"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
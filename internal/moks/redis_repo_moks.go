@@ -16,7 +16,42 @@ func (m *MockRedisRepo) Set(ctx context.Context, key string, value interface{},
 	return args.Error(0)
 }
 
+func (m *MockRedisRepo) Get(ctx context.Context, key string) (string, error) {
+	args := m.Called(ctx, key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRedisRepo) GetDel(ctx context.Context, key string) (string, error) {
+	args := m.Called(ctx, key)
+	return args.String(0), args.Error(1)
+}
+
 func (m *MockRedisRepo) Exists(ctx context.Context, redisKey string) (bool, error) {
 	args := m.Called(ctx, redisKey)
 	return args.Get(0).(bool), args.Error(1)
 }
+
+func (m *MockRedisRepo) Del(ctx context.Context, keys ...string) error {
+	args := m.Called(ctx, keys)
+	return args.Error(0)
+}
+
+func (m *MockRedisRepo) Incr(ctx context.Context, key string) (int64, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRedisRepo) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	args := m.Called(ctx, key, expiration)
+	return args.Error(0)
+}
+
+func (m *MockRedisRepo) TTL(ctx context.Context, key string) (time.Duration, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockRedisRepo) CompareAndSet(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error) {
+	args := m.Called(ctx, key, old, value, expiration)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,60 @@
+package moks
+
+import (
+	"context"
+	"simple_gin_server/internal/sessions"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSessionRepo struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepo) CreateSession(ctx context.Context, session *sessions.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) GetSessionById(ctx context.Context, id string) (*sessions.Session, error) {
+	args := m.Called(ctx, id)
+	session, _ := args.Get(0).(*sessions.Session)
+	return session, args.Error(1)
+}
+
+func (m *MockSessionRepo) GetUserSessions(ctx context.Context, userId int) ([]sessions.Session, error) {
+	args := m.Called(ctx, userId)
+	list, _ := args.Get(0).([]sessions.Session)
+	return list, args.Error(1)
+}
+
+func (m *MockSessionRepo) RotateSession(ctx context.Context, id, oldJti, newJti string, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, oldJti, newJti, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionRepo) DeleteSession(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) DeleteUserSessionByJti(ctx context.Context, userId int, jti string) error {
+	args := m.Called(ctx, userId, jti)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) DeleteUserSession(ctx context.Context, userId int, id string) error {
+	args := m.Called(ctx, userId, id)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) DeleteUserSessions(ctx context.Context, userId int) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) DeleteOtherUserSessions(ctx context.Context, userId int, keepId string) error {
+	args := m.Called(ctx, userId, keepId)
+	return args.Error(0)
+}
@@ -3,6 +3,7 @@ package moks
 import (
 	"context"
 	"simple_gin_server/internal/users"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -11,9 +12,9 @@ type MockUserRepo struct {
 	mock.Mock
 }
 
-func (m *MockUserRepo) AddUser(ctx context.Context, email, hashed_pass, role string, is_active bool) error {
+func (m *MockUserRepo) AddUser(ctx context.Context, email, hashed_pass, role string, is_active bool) (int, error) {
 	args := m.Called(ctx, email, hashed_pass, role, is_active)
-	return args.Error(0)
+	return args.Int(0), args.Error(1)
 }
 
 func (m *MockUserRepo) FindByEmail(ctx context.Context, email string) (*users.User, error) {
@@ -21,6 +22,11 @@ func (m *MockUserRepo) FindByEmail(ctx context.Context, email string) (*users.Us
 	return args.Get(0).(*users.User), args.Error(1)
 }
 
+func (m *MockUserRepo) FindById(ctx context.Context, id int) (*users.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*users.User), args.Error(1)
+}
+
 func (m *MockUserRepo) GetEmailLIst(ctx context.Context) ([]string, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]string), args.Error(1)
@@ -31,17 +37,43 @@ func (m *MockUserRepo) CheckIfInBaseByEmail(ctx context.Context, email string) (
 	return args.Get(0).(bool), args.Error(1)
 }
 
-func (m *MockUserRepo) AddRefreshToken(ctx context.Context, email, refreshToken string) error {
-	args := m.Called(ctx, email, refreshToken)
+func (m *MockUserRepo) EnsureAdminExists(ctx context.Context) error {
+	args := m.Called(ctx)
 	return args.Error(0)
 }
 
-func (m *MockUserRepo) ClearRefreshToken(ctx context.Context, claimsEmail string) error {
-	args := m.Called(ctx, claimsEmail)
+func (m *MockUserRepo) DeleteUserById(ctx context.Context, id int) (*users.User, error) {
+	args := m.Called(ctx, id)
+	user, _ := args.Get(0).(*users.User)
+	return user, args.Error(1)
+}
+
+func (m *MockUserRepo) GetTokenState(ctx context.Context, id int) (int, bool, error) {
+	args := m.Called(ctx, id)
+	return args.Int(0), args.Bool(1), args.Error(2)
+}
+
+func (m *MockUserRepo) IncrementTokenVersion(ctx context.Context, id int) (int, error) {
+	args := m.Called(ctx, id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepo) VerifyEmail(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockUserRepo) EnsureAdminExists(ctx context.Context) error {
-	args := m.Called(ctx)
+func (m *MockUserRepo) CreatePasswordReset(ctx context.Context, userId int, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, userId, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) ConsumePasswordReset(ctx context.Context, tokenHash string) (int, time.Time, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Int(0), args.Get(1).(time.Time), args.Error(2)
+}
+
+func (m *MockUserRepo) UpdatePassword(ctx context.Context, id int, hashedPass string) error {
+	args := m.Called(ctx, id, hashedPass)
 	return args.Error(0)
 }
@@ -0,0 +1,11 @@
+package profile
+
+import "errors"
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrNickNameTaken   = errors.New("profile with such nick_name already exists")
+	ErrReadOnlyField   = errors.New("field can not be changed")
+	ErrUnknownField    = errors.New("unknown profile field")
+	ErrInvalidProfile  = errors.New("invalid profile")
+)
@@ -1,8 +1,11 @@
 package profile
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"simple_gin_server/pkg/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,23 +28,18 @@ func (p *ProfileHandler) CreateNewProfileHandler(c *gin.Context) {
 		return
 	}
 
-	// пробуем извлеч Email из контектста
-	email, exists := c.Get("user_email")
-	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found"})
-		return
-	}
-
-	// Делаем приведение типа к string
-	emailStr, ok := email.(string) // Приведение типа
+	emailStr, ok := middleware.UserEmail(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Wrong Email type"})
 		return
 	}
 
 	// Создаём новый профиль
 	err := p.service.CreateNewProfile(c, &profile, emailStr)
 	if err != nil {
+		if errors.Is(err, ErrInvalidProfile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create new profile"})
 		return
 	}
@@ -52,10 +50,68 @@ func (p *ProfileHandler) CreateNewProfileHandler(c *gin.Context) {
 }
 
 // Хэндлер для получения своего профиля
-func (p *ProfileHandler) GetMyProfileHandler(c *gin.Context) {}
+func (p *ProfileHandler) GetMyProfileHandler(c *gin.Context) {
+	emailStr, ok := middleware.UserEmail(c)
+	if !ok {
+		return
+	}
+
+	profile, err := p.service.GetMyProfile(c, emailStr)
+	if err != nil {
+		writeServiceError(c, err, "Failed to get profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// Хэндлер для оновления своего профиля (PATCH, передаются только изменяемые поля)
+func (p *ProfileHandler) UpdateMyProfileHandler(c *gin.Context) {
+	var patch map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&patch); err != nil || len(patch) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	emailStr, ok := middleware.UserEmail(c)
+	if !ok {
+		return
+	}
 
-// Хэндлер для оновления своего профиля
-func (p *ProfileHandler) UpdateMyProfileHandler(c *gin.Context) {}
+	profile, err := p.service.UpdateMyProfile(c, emailStr, patch)
+	if err != nil {
+		writeServiceError(c, err, "Failed to update profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
 
 // Хэндлер для удаления своего профиля
-func (p *ProfileHandler) DeleteMyProfileHandler(c *gin.Context) {}
+func (p *ProfileHandler) DeleteMyProfileHandler(c *gin.Context) {
+	emailStr, ok := middleware.UserEmail(c)
+	if !ok {
+		return
+	}
+
+	if err := p.service.DeleteMyProfile(c, emailStr); err != nil {
+		writeServiceError(c, err, "Failed to delete profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"Message": "Profile has been deleted"})
+}
+
+// пишет ответ с кодом, соответствующим ошибке сервисного слоя
+func writeServiceError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrProfileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidProfile), errors.Is(err, ErrReadOnlyField), errors.Is(err, ErrUnknownField):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNickNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
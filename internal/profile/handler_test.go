@@ -0,0 +1,194 @@
+package profile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"simple_gin_server/internal/moks"
+	"simple_gin_server/internal/users"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// мок репозитория профилей (лежит здесь, т.к. moks не может импортировать profile без цикла)
+type mockProfileRepo struct {
+	mock.Mock
+}
+
+func (m *mockProfileRepo) CheckPlrofileInBase(ctx context.Context, nickName string) (bool, error) {
+	args := m.Called(ctx, nickName)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockProfileRepo) SaveProfile(ctx context.Context, profile *Profile, userId string) error {
+	args := m.Called(ctx, profile, userId)
+	return args.Error(0)
+}
+
+func (m *mockProfileRepo) GetProfileByUserId(ctx context.Context, userId string) (*Profile, error) {
+	args := m.Called(ctx, userId)
+	prof, _ := args.Get(0).(*Profile)
+	return prof, args.Error(1)
+}
+
+func (m *mockProfileRepo) UpdateProfile(ctx context.Context, profile *Profile) error {
+	args := m.Called(ctx, profile)
+	return args.Error(0)
+}
+
+func (m *mockProfileRepo) DeleteProfileByUserId(ctx context.Context, userId string) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+// функция настройки тестового окружения: хэндлеры /profiles/me поверх настоящего сервиса с моками репозиториев,
+// Email авторизованного пользователя кладётся в контекст, как это делает AuthMiddleware
+func setUpProfileHandlerTest(t *testing.T) (*mockProfileRepo, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+
+	repoProf := new(mockProfileRepo)
+	repoUser := new(moks.MockUserRepo)
+	repoUser.On("FindByEmail", mock.Anything, "test@example.com").Return(&users.User{Id: 7, Email: "test@example.com"}, nil)
+	handler := NewProfileHandler(NewProfileService(repoProf, repoUser))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_email", "test@example.com")
+		c.Next()
+	})
+	router.GET("/profiles/me", handler.GetMyProfileHandler)
+	router.PATCH("/profiles/me", handler.UpdateMyProfileHandler)
+	router.DELETE("/profiles/me", handler.DeleteMyProfileHandler)
+
+	return repoProf, router
+}
+
+// профиль пользователя 7 в базе
+func storedProfile() *Profile {
+	return &Profile{
+		ID:         "id",
+		UserID:     "7",
+		Name:       "Алексей",
+		NickName:   "alex",
+		City:       "Москва",
+		Profession: "IT",
+		Hobbies:    []string{"music"},
+	}
+}
+
+// тест получения своего профиля
+func TestGetMyProfileHandler(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		repoProf, router := setUpProfileHandlerTest(t)
+		repoProf.On("GetProfileByUserId", mock.Anything, "7").Return(storedProfile(), nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/profiles/me", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"nick_name":"alex"`)
+	})
+
+	t.Run("no profile", func(t *testing.T) {
+		repoProf, router := setUpProfileHandlerTest(t)
+		repoProf.On("GetProfileByUserId", mock.Anything, "7").Return(nil, ErrProfileNotFound)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/profiles/me", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// тест частичного обновления своего профиля
+func TestUpdateMyProfileHandler(t *testing.T) {
+	patch := func(router *gin.Engine, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/profiles/me", strings.NewReader(body)))
+		return w
+	}
+
+	t.Run("null resets field, missing fields are kept", func(t *testing.T) {
+		repoProf, router := setUpProfileHandlerTest(t)
+		repoProf.On("GetProfileByUserId", mock.Anything, "7").Return(storedProfile(), nil)
+		repoProf.On("UpdateProfile", mock.Anything, mock.MatchedBy(func(p *Profile) bool {
+			return p.Profession == "" && p.Hobbies == nil && p.City == "Казань" && p.Name == "Алексей" && p.NickName == "alex"
+		})).Return(nil)
+
+		w := patch(router, `{"profession":null,"hobbies":null,"city":"Казань"}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"profession":""`)
+		repoProf.AssertExpectations(t)
+		repoProf.AssertNotCalled(t, "CheckPlrofileInBase", mock.Anything, mock.Anything)
+	})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "null required field", body: `{"name":null}`},
+		{name: "invalid gender", body: `{"gender":"robot"}`},
+		{name: "read-only field", body: `{"rating":100}`},
+		{name: "unknown field", body: `{"age":30}`},
+		{name: "mistyped field", body: `{"city":42}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoProf, router := setUpProfileHandlerTest(t)
+			repoProf.On("GetProfileByUserId", mock.Anything, "7").Return(storedProfile(), nil)
+
+			w := patch(router, tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			repoProf.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("empty patch", func(t *testing.T) {
+		repoProf, router := setUpProfileHandlerTest(t)
+
+		w := patch(router, `{}`)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		repoProf.AssertNotCalled(t, "GetProfileByUserId", mock.Anything, mock.Anything)
+	})
+
+	t.Run("no profile", func(t *testing.T) {
+		repoProf, router := setUpProfileHandlerTest(t)
+		repoProf.On("GetProfileByUserId", mock.Anything, "7").Return(nil, ErrProfileNotFound)
+
+		w := patch(router, `{"city":"Казань"}`)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// тест удаления своего профиля
+func TestDeleteMyProfileHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		repoErr    error
+		wantStatus int
+	}{
+		{name: "deleted", wantStatus: http.StatusOK},
+		{name: "no profile", repoErr: ErrProfileNotFound, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoProf, router := setUpProfileHandlerTest(t)
+			repoProf.On("DeleteProfileByUserId", mock.Anything, "7").Return(tt.repoErr)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/profiles/me", nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			repoProf.AssertExpectations(t)
+		})
+	}
+}
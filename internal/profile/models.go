@@ -3,19 +3,19 @@ package profile
 import "time"
 
 type Profile struct {
-	ID         string    `json:"id"`          // UUID профиля (primary key)
-	UserID     string    `json:"user_id"`     // id пользователя (foreign key)
-	Name       string    `json:"name"`        // "Алексей"
-	NickName   string    `json:"nick_name"`   // прозвище, должно быть уникальным
-	Gender     string    `json:"gender"`      // "male", "female"
-	AgeGroup   string    `json:"age_group"`   // "18-20", "21-25"
-	City       string    `json:"city"`        // "Москва"
-	Profession string    `json:"profession"`  // "IT", "doctor"
-	Smoking    string    `json:"smoking"`     // "none", "sometimes"
-	Goal       string    `json:"goal"`        // "dating", "friendship"
-	Hobbies    []string  `json:"hobbies"`     // ["travel", "music"]
-	SocialLink string    `json:"social_link"` // "tg://username"
-	Rating     int64     `json:"rating"`      // при регистрации устанавливается в 10 единиц
-	CreatedAt  time.Time `json:"created_at"`  // дата создания
-	UpdatedAt  time.Time `json:"updated_at"`  // дата обновления
+	ID         string    `json:"id"`                                                          // UUID профиля (primary key)
+	UserID     string    `json:"user_id"`                                                     // id пользователя (foreign key)
+	Name       string    `json:"name" validate:"required,max=255"`                            // "Алексей"
+	NickName   string    `json:"nick_name" validate:"required,max=255"`                       // прозвище, должно быть уникальным
+	Gender     string    `json:"gender" validate:"omitempty,oneof=male female"`               // "male", "female"
+	AgeGroup   string    `json:"age_group" validate:"max=32"`                                 // "18-20", "21-25"
+	City       string    `json:"city" validate:"max=255"`                                     // "Москва"
+	Profession string    `json:"profession" validate:"max=255"`                               // "IT", "doctor"
+	Smoking    string    `json:"smoking" validate:"omitempty,oneof=none sometimes regularly"` // "none", "sometimes"
+	Goal       string    `json:"goal" validate:"max=32"`                                      // "dating", "friendship"
+	Hobbies    []string  `json:"hobbies" validate:"max=20,dive,required,max=64"`              // ["travel", "music"]
+	SocialLink string    `json:"social_link" validate:"max=255"`                              // "tg://username"
+	Rating     int64     `json:"rating"`                                                      // при регистрации устанавливается в 10 единиц
+	CreatedAt  time.Time `json:"created_at"`                                                  // дата создания
+	UpdatedAt  time.Time `json:"updated_at"`                                                  // дата обновления
 }
@@ -2,27 +2,38 @@ package profile
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"simple_gin_server/pkg/db"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 )
 
+// код ошибки postgres при нарушении уникальности
+const pgUniqueViolation = "23505"
+
 // Интерфейс для слоя ordersRepository для использования другими источниками
 type ProfileRepoInterface interface {
 	CheckPlrofileInBase(ctx context.Context, nickName string) (bool, error)
 	SaveProfile(ctx context.Context, profile *Profile, userId string) error
+	GetProfileByUserId(ctx context.Context, userId string) (*Profile, error)
+	UpdateProfile(ctx context.Context, profile *Profile) error
+	DeleteProfileByUserId(ctx context.Context, userId string) error
 }
 
 type ProfileRepository struct {
 	Database db.PgRepoInterface
+	log      *slog.Logger
 }
 
 // Конструктор репозитория
-func NewProfileRepository(dataBase db.PgRepoInterface) *ProfileRepository {
+func NewProfileRepository(dataBase db.PgRepoInterface, log *slog.Logger) *ProfileRepository {
 	return &ProfileRepository{
 		Database: dataBase,
+		log:      log,
 	}
 }
 
@@ -52,11 +63,17 @@ func (p *ProfileRepository) SaveProfile(ctx context.Context, profile *Profile, u
 		return err
 	}
 
+	// колонка hobbies NOT NULL, пустой список вместо NULL
+	hobbies := profile.Hobbies
+	if hobbies == nil {
+		hobbies = []string{}
+	}
+
 	query := `INSERT INTO profiles (id, user_id, name, nick_name, gender, age_group, city, profession, smoking, goal, hobbies, social_link, rating) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
 
 	_, err := p.Database.GetPool().Exec(ctx, query,
 		uuid.New().String(),
-		profile.UserID,
+		userId,
 		profile.Name,
 		profile.NickName,
 		profile.Gender,
@@ -65,14 +82,124 @@ func (p *ProfileRepository) SaveProfile(ctx context.Context, profile *Profile, u
 		profile.Profession,
 		profile.Smoking,
 		profile.Goal,
-		profile.Hobbies,
+		hobbies,
 		profile.SocialLink,
 		10,
 	)
 
 	if err != nil {
-		log.Printf("[profile--repo.go]--Error during saving profile to DB:%v", err.Error())
+		p.log.ErrorContext(ctx, "failed to save profile", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Метод репоизтория profile для получения профиля пользователя по его id
+func (p *ProfileRepository) GetProfileByUserId(ctx context.Context, userId string) (*Profile, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id::text, user_id::text, name, nick_name, gender, age_group, city, profession,
+			smoking, goal, hobbies, social_link, rating, created_at, updated_at
+		FROM profiles
+		WHERE user_id = $1
+	`
+
+	var profile Profile
+	err := p.Database.GetPool().QueryRow(ctx, query, userId).Scan(
+		&profile.ID,
+		&profile.UserID,
+		&profile.Name,
+		&profile.NickName,
+		&profile.Gender,
+		&profile.AgeGroup,
+		&profile.City,
+		&profile.Profession,
+		&profile.Smoking,
+		&profile.Goal,
+		&profile.Hobbies,
+		&profile.SocialLink,
+		&profile.Rating,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to query profile: %w", err)
+	}
+
+	return &profile, nil
+}
+
+// Метод репоизтория profile для сохранения изменённых полей профиля, обновляет UpdatedAt
+func (p *ProfileRepository) UpdateProfile(ctx context.Context, profile *Profile) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
 		return err
 	}
+
+	// колонка hobbies NOT NULL, пустой список вместо NULL
+	hobbies := profile.Hobbies
+	if hobbies == nil {
+		hobbies = []string{}
+	}
+
+	query := `
+		UPDATE profiles
+		SET name = $2, nick_name = $3, gender = $4, age_group = $5, city = $6, profession = $7,
+			smoking = $8, goal = $9, hobbies = $10, social_link = $11, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := p.Database.GetPool().QueryRow(ctx, query,
+		profile.ID,
+		profile.Name,
+		profile.NickName,
+		profile.Gender,
+		profile.AgeGroup,
+		profile.City,
+		profile.Profession,
+		profile.Smoking,
+		profile.Goal,
+		hobbies,
+		profile.SocialLink,
+	).Scan(&profile.UpdatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrProfileNotFound
+		case errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation:
+			return ErrNickNameTaken
+		}
+		p.log.ErrorContext(ctx, "failed to update profile", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Метод репоизтория profile для удаления профиля пользователя (совпадения и действия удаляются каскадно)
+func (p *ProfileRepository) DeleteProfileByUserId(ctx context.Context, userId string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := p.Database.GetPool().Exec(ctx, `DELETE FROM profiles WHERE user_id = $1`, userId)
+	if err != nil {
+		p.log.ErrorContext(ctx, "failed to delete profile", "error", err)
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrProfileNotFound
+	}
 	return nil
 }
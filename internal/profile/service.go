@@ -1,14 +1,24 @@
 package profile
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
 	"simple_gin_server/internal/users"
 	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator"
 )
 
 type ServiceInterface interface {
 	CreateNewProfile(ctx context.Context, profile *Profile, email string) error
+	GetMyProfile(ctx context.Context, email string) (*Profile, error)
+	UpdateMyProfile(ctx context.Context, email string, patch map[string]json.RawMessage) (*Profile, error)
+	DeleteMyProfile(ctx context.Context, email string) error
 }
 
 // Интерфейс для слоя ordersService для использования другими источниками
@@ -30,6 +40,10 @@ func (p *ProfileService) CreateNewProfile(ctx context.Context, profile *Profile,
 		return err
 	}
 
+	if err := validateProfile(profile); err != nil {
+		return err
+	}
+
 	// Проверяем есть ли такой profile в базе
 
 	profileInBase, err := p.repoProf.CheckPlrofileInBase(ctx, profile.NickName)
@@ -38,6 +52,9 @@ func (p *ProfileService) CreateNewProfile(ctx context.Context, profile *Profile,
 	}
 
 	user, err := p.repoUser.FindByEmail(ctx, email)
+	if err != nil {
+		return errors.New("[profile--service.go] - Failed to find profile owner")
+	}
 
 	if !profileInBase {
 		err := p.repoProf.SaveProfile(ctx, profile, strconv.Itoa(user.Id))
@@ -48,3 +65,148 @@ func (p *ProfileService) CreateNewProfile(ctx context.Context, profile *Profile,
 
 	return nil
 }
+
+// Получение профиля пользователя с заданным Email
+func (p *ProfileService) GetMyProfile(ctx context.Context, email string) (*Profile, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	user, err := p.repoUser.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.repoProf.GetProfileByUserId(ctx, strconv.Itoa(user.Id))
+}
+
+// Частичное обновление профиля пользователя с заданным Email (семантика JSON merge patch:
+// отсутствующие поля не меняются, null сбрасывает поле в пустое значение)
+func (p *ProfileService) UpdateMyProfile(ctx context.Context, email string, patch map[string]json.RawMessage) (*Profile, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	profile, err := p.GetMyProfile(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	oldNickName := profile.NickName
+	if err := applyMergePatch(profile, patch); err != nil {
+		return nil, err
+	}
+
+	if err := validateProfile(profile); err != nil {
+		return nil, err
+	}
+
+	// новый nickName не должен быть занят другим профилем
+	if profile.NickName != oldNickName {
+		taken, err := p.repoProf.CheckPlrofileInBase(ctx, profile.NickName)
+		if err != nil {
+			return nil, errors.New("[profile--service.go] - Failed to check profile in base")
+		}
+		if taken {
+			return nil, ErrNickNameTaken
+		}
+	}
+
+	if err := p.repoProf.UpdateProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// Удаление профиля пользователя с заданным Email
+func (p *ProfileService) DeleteMyProfile(ctx context.Context, email string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	user, err := p.repoUser.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	return p.repoProf.DeleteProfileByUserId(ctx, strconv.Itoa(user.Id))
+}
+
+// применяет к профилю изменения из patch (ключи - json имена полей профиля)
+func applyMergePatch(profile *Profile, patch map[string]json.RawMessage) error {
+	stringFields := map[string]*string{
+		"name":        &profile.Name,
+		"nick_name":   &profile.NickName,
+		"gender":      &profile.Gender,
+		"age_group":   &profile.AgeGroup,
+		"city":        &profile.City,
+		"profession":  &profile.Profession,
+		"smoking":     &profile.Smoking,
+		"goal":        &profile.Goal,
+		"social_link": &profile.SocialLink,
+	}
+
+	for key, raw := range patch {
+		isNull := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+
+		if dst, ok := stringFields[key]; ok {
+			if isNull {
+				*dst = ""
+				continue
+			}
+			if err := json.Unmarshal(raw, dst); err != nil {
+				return fmt.Errorf("%w: %s must be a string", ErrInvalidProfile, key)
+			}
+			continue
+		}
+
+		switch key {
+		case "hobbies":
+			var hobbies []string
+			if !isNull {
+				if err := json.Unmarshal(raw, &hobbies); err != nil {
+					return fmt.Errorf("%w: %s must be an array of strings", ErrInvalidProfile, key)
+				}
+			}
+			profile.Hobbies = hobbies
+		case "id", "user_id", "rating", "created_at", "updated_at":
+			return fmt.Errorf("%w: %s", ErrReadOnlyField, key)
+		default:
+			return fmt.Errorf("%w: %s", ErrUnknownField, key)
+		}
+	}
+
+	return nil
+}
+
+// валидатор профиля, в ошибках используются json имена полей
+var validate = func() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	})
+	return v
+}()
+
+// проверяет поля профиля по тегам validate
+func validateProfile(profile *Profile) error {
+	err := validate.Struct(profile)
+	if err == nil {
+		return nil
+	}
+
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	details := make([]string, 0, len(validationErrors))
+	for _, e := range validationErrors {
+		details = append(details, fmt.Sprintf("%s: %s", e.Field(), e.Tag()))
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidProfile, strings.Join(details, ", "))
+}
@@ -0,0 +1,77 @@
+package profile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// тест применения JSON merge patch к профилю
+func TestApplyMergePatch(t *testing.T) {
+	base := func() *Profile {
+		return &Profile{
+			ID:         "id",
+			Name:       "Алексей",
+			NickName:   "alex",
+			City:       "Москва",
+			Profession: "IT",
+			Hobbies:    []string{"music"},
+		}
+	}
+	parse := func(t *testing.T, body string) map[string]json.RawMessage {
+		var patch map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal([]byte(body), &patch))
+		return patch
+	}
+
+	t.Run("changes only given fields", func(t *testing.T) {
+		p := base()
+
+		err := applyMergePatch(p, parse(t, `{"city":"Казань","hobbies":["travel","books"]}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Казань", p.City)
+		assert.Equal(t, []string{"travel", "books"}, p.Hobbies)
+		assert.Equal(t, "Алексей", p.Name)
+		assert.Equal(t, "IT", p.Profession)
+	})
+
+	t.Run("null resets field", func(t *testing.T) {
+		p := base()
+
+		err := applyMergePatch(p, parse(t, `{"profession":null,"hobbies":null}`))
+
+		assert.NoError(t, err)
+		assert.Empty(t, p.Profession)
+		assert.Empty(t, p.Hobbies)
+	})
+
+	t.Run("read-only, unknown and mistyped fields", func(t *testing.T) {
+		assert.ErrorIs(t, applyMergePatch(base(), parse(t, `{"rating":100}`)), ErrReadOnlyField)
+		assert.ErrorIs(t, applyMergePatch(base(), parse(t, `{"user_id":"2"}`)), ErrReadOnlyField)
+		assert.ErrorIs(t, applyMergePatch(base(), parse(t, `{"age":30}`)), ErrUnknownField)
+		assert.ErrorIs(t, applyMergePatch(base(), parse(t, `{"city":42}`)), ErrInvalidProfile)
+	})
+}
+
+// тест валидации полей профиля
+func TestValidateProfile(t *testing.T) {
+	valid := Profile{Name: "Алексей", NickName: "alex", Gender: "male", Smoking: "none", Hobbies: []string{"music"}}
+	assert.NoError(t, validateProfile(&valid))
+
+	noName := valid
+	noName.Name = ""
+	err := validateProfile(&noName)
+	assert.ErrorIs(t, err, ErrInvalidProfile)
+	assert.Contains(t, err.Error(), "name: required")
+
+	badGender := valid
+	badGender.Gender = "robot"
+	assert.ErrorIs(t, validateProfile(&badGender), ErrInvalidProfile)
+
+	emptyHobby := valid
+	emptyHobby.Hobbies = []string{""}
+	assert.ErrorIs(t, validateProfile(&emptyHobby), ErrInvalidProfile)
+}
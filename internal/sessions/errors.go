@@ -0,0 +1,7 @@
+package sessions
+
+import "errors"
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
@@ -0,0 +1,27 @@
+package sessions
+
+import "time"
+
+// Сессия пользователя: один логин на одном устройстве со своим refresh токеном
+type Session struct {
+	ID         string    `json:"id"`           // UUID сессии, совпадает с семейством refresh токенов (claim fid)
+	UserID     int       `json:"-"`            // id владельца сессии
+	Jti        string    `json:"-"`            // jti текущего refresh токена сессии
+	DeviceName string    `json:"device_name"`  // "iPhone Алексея", задаётся клиентом при логине
+	UserAgent  string    `json:"user_agent"`   // заголовок User-Agent при логине
+	IP         string    `json:"ip"`           // IP адрес клиента при логине
+	CreatedAt  time.Time `json:"created_at"`   // время логина
+	LastUsedAt time.Time `json:"last_used_at"` // время последней ротации refresh токена
+	ExpiresAt  time.Time `json:"expires_at"`   // время истечения текущего refresh токена
+}
+
+// Максимальная длина User-Agent в символах (колонка sessions.user_agent VARCHAR(512)),
+// более длинный заголовок обрезается
+const MaxUserAgentLen = 512
+
+// Данные клиента, сохраняемые при создании сессии
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
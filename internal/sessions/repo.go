@@ -0,0 +1,249 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"simple_gin_server/pkg/db"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Интерфейс для слоя sessionRepository для использования другими источниками
+type SessionRepoInterface interface {
+	CreateSession(ctx context.Context, session *Session) error
+	GetSessionById(ctx context.Context, id string) (*Session, error)
+	GetUserSessions(ctx context.Context, userId int) ([]Session, error)
+	RotateSession(ctx context.Context, id, oldJti, newJti string, expiresAt time.Time) (bool, error)
+	DeleteSession(ctx context.Context, id string) error
+	DeleteUserSessionByJti(ctx context.Context, userId int, jti string) error
+	DeleteUserSession(ctx context.Context, userId int, id string) error
+	DeleteUserSessions(ctx context.Context, userId int) error
+	DeleteOtherUserSessions(ctx context.Context, userId int, keepId string) error
+}
+
+type SessionRepository struct {
+	Database db.PgRepoInterface
+	log      *slog.Logger
+}
+
+// Конструктор репозитория
+func NewSessionRepository(dataBase db.PgRepoInterface, log *slog.Logger) *SessionRepository {
+	return &SessionRepository{
+		Database: dataBase,
+		log:      log,
+	}
+}
+
+// список колонок сессии, общий для всех выборок из таблицы sessions
+const sessionColumns = `id::text, user_id, jti::text, device_name, user_agent, ip, created_at, last_used_at, expires_at`
+
+// сканирует строку выборки с колонками sessionColumns
+func scanSession(row pgx.Row) (*Session, error) {
+	var s Session
+	err := row.Scan(
+		&s.ID,
+		&s.UserID,
+		&s.Jti,
+		&s.DeviceName,
+		&s.UserAgent,
+		&s.IP,
+		&s.CreatedAt,
+		&s.LastUsedAt,
+		&s.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Сохраняет новую сессию (при логине)
+func (r *SessionRepository) CreateSession(ctx context.Context, session *Session) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO sessions (id, user_id, jti, device_name, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, last_used_at
+	`
+	err := r.Database.GetPool().QueryRow(ctx, query,
+		session.ID,
+		session.UserID,
+		session.Jti,
+		session.DeviceName,
+		session.UserAgent,
+		session.IP,
+		session.ExpiresAt,
+	).Scan(&session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		r.log.ErrorContext(ctx, "failed to create session", "error", err)
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+// Получение сессии по её id
+func (r *SessionRepository) GetSessionById(ctx context.Context, id string) (*Session, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+
+	session, err := scanSession(r.Database.GetPool().QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return session, nil
+}
+
+// Получение действующих сессий пользователя, последние использованные - первыми
+func (r *SessionRepository) GetUserSessions(ctx context.Context, userId int) ([]Session, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`
+
+	rows, err := r.Database.GetPool().Query(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// атомарно заменяет jti refresh токена сессии, только если в БД всё ещё хранится oldJti.
+// false означает, что токен oldJti уже был заменён (повторное использование или гонка двух ротаций)
+func (r *SessionRepository) RotateSession(ctx context.Context, id, oldJti, newJti string, expiresAt time.Time) (bool, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	const query = `
+		UPDATE sessions
+		SET jti = $1, expires_at = $2, last_used_at = NOW()
+		WHERE id = $3 AND jti = $4
+	`
+	res, err := r.Database.GetPool().Exec(ctx, query, newJti, expiresAt, id, oldJti)
+	if err != nil {
+		r.log.ErrorContext(ctx, "failed to rotate session", "error", err)
+		return false, err
+	}
+
+	return res.RowsAffected() == 1, nil
+}
+
+// Удаление сессии по id (отзыв всех refresh токенов сессии)
+func (r *SessionRepository) DeleteSession(ctx context.Context, id string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := r.Database.GetPool().Exec(ctx, `DELETE FROM sessions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	return nil
+}
+
+// Удаление сессии пользователя, текущий refresh токен которой имеет заданный jti (logout), чужие сессии не удаляются
+func (r *SessionRepository) DeleteUserSessionByJti(ctx context.Context, userId int, jti string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := r.Database.GetPool().Exec(ctx, `DELETE FROM sessions WHERE jti = $1 AND user_id = $2`, jti, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// Удаление сессии пользователя по id, чужие сессии не удаляются
+func (r *SessionRepository) DeleteUserSession(ctx context.Context, userId int, id string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	res, err := r.Database.GetPool().Exec(ctx, `DELETE FROM sessions WHERE id = $1 AND user_id = $2`, id, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// Удаление всех сессий пользователя (logout со всех устройств)
+func (r *SessionRepository) DeleteUserSessions(ctx context.Context, userId int) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := r.Database.GetPool().Exec(ctx, `DELETE FROM sessions WHERE user_id = $1`, userId)
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	return nil
+}
+
+// Удаление всех сессий пользователя, кроме keepId (смена пароля: текущее устройство остаётся залогиненным)
+func (r *SessionRepository) DeleteOtherUserSessions(ctx context.Context, userId int, keepId string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := r.Database.GetPool().Exec(ctx, `DELETE FROM sessions WHERE user_id = $1 AND id::text <> $2`, userId, keepId)
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	return nil
+}
@@ -1,7 +1,14 @@
 package users
 
+import "errors"
+
 const (
 	ErrUserExists       = "пользователь с таким email уже существует"
 	ErrUserNotExists    = "пользователь с таким email НЕ существует"
 	ErrWrongCredentials = "wrong email or password"
 )
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrResetTokenNotFound = errors.New("password reset token not found")
+)
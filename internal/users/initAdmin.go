@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"os"
 
 	"golang.org/x/crypto/bcrypt"
@@ -34,7 +33,7 @@ func (r *UserRepository) EnsureAdminExists(ctx context.Context) error {
 
 	// если admin уже есть, то выходим
 	if exists {
-		log.Println("admin user already exists")
+		r.log.InfoContext(ctx, "admin user already exists")
 		return nil
 	}
 
@@ -50,11 +49,11 @@ func (r *UserRepository) EnsureAdminExists(ctx context.Context) error {
 		Is_Active:  true,
 	}
 
-	err = r.AddUser(ctx, admin.AdminEmail, admin.HashedPass, admin.Role, admin.Is_Active)
+	_, err = r.AddUser(ctx, admin.AdminEmail, admin.HashedPass, admin.Role, admin.Is_Active)
 	if err != nil {
 		return err
 	}
-	log.Println("Admin user was added to DB")
+	r.log.InfoContext(ctx, "admin user was added to DB")
 
 	return nil
 }
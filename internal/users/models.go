@@ -1,12 +1,13 @@
 package users
 
 type User struct {
-	Id           int
-	Email        string
-	HashPass     string
-	RefreshToken string
-	Role         string
-	IsActive     bool
+	Id            int
+	Email         string
+	HashPass      string
+	Role          string
+	IsActive      bool
+	EmailVerified bool // email подтверждён переходом по ссылке из письма
+	TokenVersion  int  // версия токенов, увеличивается при logout со всех устройств
 }
 
 type AdminConfig struct {
@@ -0,0 +1,90 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Сохранение хэша токена сброса пароля
+func (r *UserRepository) CreatePasswordReset(ctx context.Context, userId int, tokenHash string, expiresAt time.Time) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO password_reset_tokens (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`
+	if _, err := r.Database.GetPool().Exec(ctx, query, tokenHash, userId, expiresAt); err != nil {
+		r.log.ErrorContext(ctx, "failed to create password reset token", "error", err)
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return nil
+}
+
+// Использование токена сброса пароля: токен удаляется (второй раз его использовать нельзя).
+// Возвращает id пользователя и время истечения токена, если токена нет - ErrResetTokenNotFound
+func (r *UserRepository) ConsumePasswordReset(ctx context.Context, tokenHash string) (int, time.Time, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return 0, time.Time{}, err
+	}
+
+	const query = `
+		DELETE FROM password_reset_tokens
+		WHERE token_hash = $1
+		RETURNING user_id, expires_at
+	`
+	var userId int
+	var expiresAt time.Time
+	err := r.Database.GetPool().QueryRow(ctx, query, tokenHash).Scan(&userId, &expiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, time.Time{}, ErrResetTokenNotFound
+		}
+		r.log.ErrorContext(ctx, "failed to consume password reset token", "error", err)
+		return 0, time.Time{}, fmt.Errorf("failed to consume password reset token: %w", err)
+	}
+
+	return userId, expiresAt, nil
+}
+
+// Замена хэша пароля пользователя, остальные токены сброса пароля пользователя удаляются
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int, hashedPass string) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx, err := r.Database.GetPool().Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, `UPDATE users SET hashed_pass = $2 WHERE id = $1`, id, hashedPass)
+	if err != nil {
+		r.log.ErrorContext(ctx, "failed to update password", "error", err)
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM password_reset_tokens WHERE user_id = $1`, id); err != nil {
+		r.log.ErrorContext(ctx, "failed to delete password reset tokens", "error", err)
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit password update: %w", err)
+	}
+
+	return nil
+}
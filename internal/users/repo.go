@@ -4,62 +4,77 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"simple_gin_server/pkg/db"
+	"time"
 
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/v4"
 )
 
 // Интерфейс для слоя userRepository для использования другими источниками
 type UserRepoInterface interface {
-	AddUser(ctx context.Context, email, hashedPass string, role string, is_active bool) error
+	AddUser(ctx context.Context, email, hashedPass string, role string, is_active bool) (int, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindById(ctx context.Context, id int) (*User, error)
 	GetEmailLIst(ctx context.Context) ([]string, error)
 	CheckIfInBaseByEmail(ctx context.Context, email string) (bool, error)
-	AddRefreshToken(ctx context.Context, email, refreshToken string) error
-	ClearRefreshToken(ctx context.Context, claimsEmail string) error
 	EnsureAdminExists(ctx context.Context) error
+	DeleteUserById(ctx context.Context, id int) (*User, error)
+	GetTokenState(ctx context.Context, id int) (int, bool, error)
+	IncrementTokenVersion(ctx context.Context, id int) (int, error)
+	VerifyEmail(ctx context.Context, id int) error
+	CreatePasswordReset(ctx context.Context, userId int, tokenHash string, expiresAt time.Time) error
+	ConsumePasswordReset(ctx context.Context, tokenHash string) (int, time.Time, error)
+	UpdatePassword(ctx context.Context, id int, hashedPass string) error
 }
 
 type UserRepository struct {
 	Database db.PgRepoInterface
+	log      *slog.Logger
 }
 
 // Конструктор репозитория
-func NewUserRepository(dataBase db.PgRepoInterface) *UserRepository {
+func NewUserRepository(dataBase db.PgRepoInterface, log *slog.Logger) *UserRepository {
 	return &UserRepository{
 		Database: dataBase,
+		log:      log,
 	}
 }
 
-// Сохранение пользователя (с хешированным паролем)
-func (r *UserRepository) AddUser(ctx context.Context, email, hashedPass string, role string, is_active bool) error {
+// Сохранение пользователя (с хешированным паролем), возвращает id нового пользователя.
+// Активный при создании пользователь (администратор) считается подтвердившим email
+func (r *UserRepository) AddUser(ctx context.Context, email, hashedPass string, role string, is_active bool) (int, error) {
 
 	// Проверяем не отменен ли контекст
 	if err := ctx.Err(); err != nil {
-		return err
+		return 0, err
 	}
 
 	// создаем экземпляр нового юзера для сохранения в БД
 	newUser := User{
-		Email:    email,
-		HashPass: hashedPass,
-		Role:     role,
-		IsActive: is_active,
-	}
-	log.Println(newUser.Email)
-	query := `INSERT INTO users (email, hashed_pass, user_role, is_active) VALUES ($1, $2, $3, $4) ON CONFLICT (email) DO NOTHING`
-	res, err := r.Database.GetPool().Exec(ctx, query, newUser.Email, newUser.HashPass, newUser.Role, newUser.IsActive)
-	if err != nil {
-		log.Println(err.Error())
-		return err
+		Email:         email,
+		HashPass:      hashedPass,
+		Role:          role,
+		IsActive:      is_active,
+		EmailVerified: is_active,
 	}
-
-	if res.RowsAffected() == 0 {
-		return errors.New("email already registered")
+	r.log.DebugContext(ctx, "adding user", "role", role)
+	query := `
+		INSERT INTO users (email, hashed_pass, role_id, is_active, email_verified)
+		SELECT $1, $2, r.id, $4, $5 FROM roles r WHERE r.name = $3
+		ON CONFLICT (email) DO NOTHING
+		RETURNING id
+	`
+	err := r.Database.GetPool().QueryRow(ctx, query, newUser.Email, newUser.HashPass, newUser.Role, newUser.IsActive, newUser.EmailVerified).Scan(&newUser.Id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, errors.New("email already registered or unknown role")
+		}
+		r.log.ErrorContext(ctx, "failed to add user", "error", err)
+		return 0, err
 	}
 
-	return nil
+	return newUser.Id, nil
 }
 
 // Проверка наличия пользователя в хранилище
@@ -70,9 +85,10 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*User,
 	}
 
 	const query = `
-		SELECT id, email, hashed_pass, COALESCE(refresh_token, '')
-		FROM users 
-		WHERE email = $1
+		SELECT u.id, u.email, u.hashed_pass, r.name, u.is_active, u.email_verified, u.token_version
+		FROM users u
+		JOIN roles r ON r.id = u.role_id
+		WHERE u.email = $1
 		LIMIT 1
 	`
 	var user User
@@ -80,20 +96,57 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*User,
 		&user.Id,
 		&user.Email,
 		&user.HashPass,
-		&user.RefreshToken,
+		&user.Role,
+		&user.IsActive,
+		&user.EmailVerified,
+		&user.TokenVersion,
 	)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("%v: %v", ErrUserNotExists, err)
 		}
-		log.Printf("function [FindByEmail], failed to query user by email: %v", err)
+		r.log.ErrorContext(ctx, "failed to query user by email", "error", err)
 		return nil, errors.New(ErrUserNotExists)
 	}
 
 	return &user, nil
 }
 
+// Получение пользователя по id, если его нет - ErrUserNotFound
+func (r *UserRepository) FindById(ctx context.Context, id int) (*User, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT u.id, u.email, u.hashed_pass, r.name, u.is_active, u.email_verified, u.token_version
+		FROM users u
+		JOIN roles r ON r.id = u.role_id
+		WHERE u.id = $1
+	`
+	var user User
+	err := r.Database.GetPool().QueryRow(ctx, query, id).Scan(
+		&user.Id,
+		&user.Email,
+		&user.HashPass,
+		&user.Role,
+		&user.IsActive,
+		&user.EmailVerified,
+		&user.TokenVersion,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		r.log.ErrorContext(ctx, "failed to query user by id", "error", err)
+		return nil, fmt.Errorf("failed to query user by id: %w", err)
+	}
+
+	return &user, nil
+}
+
 // Получение слайса email зарегестрированных пользователей
 func (r *UserRepository) GetEmailLIst(ctx context.Context) ([]string, error) {
 	// Проверяем не отменен ли контекст
@@ -149,49 +202,150 @@ func (r *UserRepository) CheckIfInBaseByEmail(ctx context.Context, email string)
 	err := r.Database.GetPool().QueryRow(ctx, query, email).Scan(&exists)
 
 	if err != nil {
-		log.Printf("ошибка при запросе в базу на поиск по Email: %v", err)
+		r.log.ErrorContext(ctx, "failed to check user existence", "error", err)
 		return false, fmt.Errorf("failed to check user existence: %w", err)
 	}
 
 	return exists, nil
 }
 
-// добавляем поле refreshToken в базу по email (нужно держать refreshToken в БД)
-func (r *UserRepository) AddRefreshToken(ctx context.Context, email, refreshToken string) error {
+// удаляет пользователя по id вместе с его профилем, совпадениями и действиями в одной транзакции.
+// Возвращает удалённого пользователя, его сессии удаляются каскадно вместе с ним
+func (r *UserRepository) DeleteUserById(ctx context.Context, id int) (*User, error) {
 	// Проверяем не отменен ли контекст
 	if err := ctx.Err(); err != nil {
-		return err
+		return nil, err
+	}
+
+	tx, err := r.Database.GetPool().Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// действия по совпадениям пользователя и действия, созданные им самим
+	const deleteActions = `
+		DELETE FROM match_actions
+		WHERE created_by IN (SELECT id FROM profiles WHERE user_id = $1)
+			OR match_id IN (
+				SELECT m.id
+				FROM matches m
+				JOIN profiles p ON p.id = m.initiator_id OR p.id = m.target_id
+				WHERE p.user_id = $1
+			)
+	`
+	const deleteMatches = `
+		DELETE FROM matches
+		WHERE initiator_id IN (SELECT id FROM profiles WHERE user_id = $1)
+			OR target_id IN (SELECT id FROM profiles WHERE user_id = $1)
+	`
+	const deleteProfile = `DELETE FROM profiles WHERE user_id = $1`
+
+	for _, query := range []string{deleteActions, deleteMatches, deleteProfile} {
+		if _, err := tx.Exec(ctx, query, id); err != nil {
+			r.log.ErrorContext(ctx, "failed to delete user data", "error", err)
+			return nil, fmt.Errorf("failed to delete user data: %w", err)
+		}
+	}
+
+	const deleteUser = `
+		DELETE FROM users u
+		USING roles r
+		WHERE u.id = $1 AND r.id = u.role_id
+		RETURNING u.id, u.email, u.hashed_pass, r.name, u.is_active, u.email_verified, u.token_version
+	`
+	var user User
+	err = tx.QueryRow(ctx, deleteUser, id).Scan(
+		&user.Id,
+		&user.Email,
+		&user.HashPass,
+		&user.Role,
+		&user.IsActive,
+		&user.EmailVerified,
+		&user.TokenVersion,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		r.log.ErrorContext(ctx, "failed to delete user", "error", err)
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit user deletion: %w", err)
+	}
+
+	return &user, nil
+}
+
+// Получение версии токенов и признака активности пользователя (проверяются для каждого access токена)
+func (r *UserRepository) GetTokenState(ctx context.Context, id int) (int, bool, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+
+	var version int
+	var isActive bool
+	err := r.Database.GetPool().QueryRow(ctx, `SELECT token_version, is_active FROM users WHERE id = $1`, id).Scan(&version, &isActive)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, ErrUserNotFound
+		}
+		return 0, false, fmt.Errorf("failed to get token state: %w", err)
+	}
+
+	return version, isActive, nil
+}
+
+// Увеличение версии токенов пользователя: все выданные ранее токены перестают приниматься.
+// Возвращает новую версию
+func (r *UserRepository) IncrementTokenVersion(ctx context.Context, id int) (int, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
 
 	const query = `
-		UPDATE users 
-		SET refresh_token = $1 
-		WHERE email = $2;
+		UPDATE users
+		SET token_version = token_version + 1
+		WHERE id = $1
+		RETURNING token_version
 	`
-	_, err := r.Database.GetPool().Exec(ctx, query, refreshToken, email)
+	var version int
+	err := r.Database.GetPool().QueryRow(ctx, query, id).Scan(&version)
 	if err != nil {
-		log.Printf("[repo.go]---[AddRefreshToken()]---Err: %v", err)
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		r.log.ErrorContext(ctx, "failed to increment token version", "error", err)
+		return 0, fmt.Errorf("failed to increment token version: %w", err)
 	}
 
-	return nil
+	return version, nil
 }
 
-// удаляет refresh токен из базы данных по заданному Email из claims
-func (r *UserRepository) ClearRefreshToken(ctx context.Context, claimsEmail string) error {
+// Подтверждение email: пользователь становится активным. Если пользователя нет
+// или email уже подтверждён - ErrUserNotFound
+func (r *UserRepository) VerifyEmail(ctx context.Context, id int) error {
 	// Проверяем не отменен ли контекст
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
 	const query = `
-		UPDATE users 
-		SET refresh_token = NULL 
-		WHERE email = $1;
+		UPDATE users
+		SET email_verified = TRUE, is_active = TRUE
+		WHERE id = $1 AND NOT email_verified
 	`
-	_, err := r.Database.GetPool().Exec(ctx, query, claimsEmail)
+	res, err := r.Database.GetPool().Exec(ctx, query, id)
 	if err != nil {
-		return err
+		r.log.ErrorContext(ctx, "failed to verify email", "error", err)
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
 	}
 
 	return nil
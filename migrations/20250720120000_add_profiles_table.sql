@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE profiles (
+    id UUID PRIMARY KEY,
+    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    nick_name VARCHAR(255) UNIQUE NOT NULL,
+    gender VARCHAR(32) NOT NULL DEFAULT '',
+    age_group VARCHAR(32) NOT NULL DEFAULT '',
+    city VARCHAR(255) NOT NULL DEFAULT '',
+    profession VARCHAR(255) NOT NULL DEFAULT '',
+    smoking VARCHAR(32) NOT NULL DEFAULT '',
+    goal VARCHAR(32) NOT NULL DEFAULT '',
+    hobbies TEXT[] NOT NULL DEFAULT '{}',
+    social_link VARCHAR(255) NOT NULL DEFAULT '',
+    rating BIGINT NOT NULL DEFAULT 10,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_profiles_search ON profiles (gender, age_group, city, goal);
+CREATE INDEX idx_profiles_hobbies ON profiles USING GIN (hobbies);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS profiles;
+-- +goose StatementEnd
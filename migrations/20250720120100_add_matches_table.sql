@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE matches (
+    id UUID PRIMARY KEY,
+    initiator_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES profiles(id) ON DELETE CASCADE,
+    compatibility INTEGER NOT NULL DEFAULT 0 CHECK (compatibility BETWEEN 0 AND 100),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'rejected')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (initiator_id <> target_id)
+);
+
+-- одно совпадение на пару профилей, независимо от того, кто его инициировал
+CREATE UNIQUE INDEX idx_matches_pair ON matches (LEAST(initiator_id, target_id), GREATEST(initiator_id, target_id));
+CREATE INDEX idx_matches_initiator_status ON matches (initiator_id, status);
+CREATE INDEX idx_matches_target_status ON matches (target_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS matches;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(64) UNIQUE NOT NULL,
+    parent_id INTEGER REFERENCES roles(id) ON DELETE SET NULL -- роль наследует все права родителя
+);
+
+CREATE TABLE role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission VARCHAR(128) NOT NULL,
+    PRIMARY KEY (role_id, permission)
+);
+
+INSERT INTO roles (name) VALUES ('user');
+INSERT INTO roles (name, parent_id) SELECT 'moderator', id FROM roles WHERE name = 'user';
+INSERT INTO roles (name, parent_id) SELECT 'admin', id FROM roles WHERE name = 'moderator';
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('user', 'profiles:read'),
+    ('user', 'profiles:write'),
+    ('user', 'matches:read'),
+    ('user', 'matches:write'),
+    ('moderator', 'users:read'),
+    ('moderator', 'matches:moderate'),
+    ('admin', 'users:write'),
+    ('admin', 'users:delete')
+) AS p(role, permission) ON p.role = r.name;
+
+-- роли пользователей хранятся ссылкой на таблицу roles вместо строки user_role
+ALTER TABLE users ADD COLUMN role_id INTEGER REFERENCES roles(id);
+UPDATE users u SET role_id = r.id FROM roles r WHERE r.name = u.user_role;
+UPDATE users SET role_id = (SELECT id FROM roles WHERE name = 'user') WHERE role_id IS NULL;
+ALTER TABLE users ALTER COLUMN role_id SET NOT NULL;
+ALTER TABLE users DROP COLUMN user_role;
+
+CREATE INDEX idx_users_role_id ON users (role_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN user_role VARCHAR(255);
+UPDATE users u SET user_role = r.name FROM roles r WHERE r.id = u.role_id;
+ALTER TABLE users ALTER COLUMN user_role SET NOT NULL;
+ALTER TABLE users DROP COLUMN role_id;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- сессия = один логин на одном устройстве, id сессии совпадает с семейством refresh токенов (claim fid)
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    jti UUID NOT NULL UNIQUE, -- jti текущего (последнего выданного) refresh токена сессии
+    device_name VARCHAR(100) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+-- refresh токены из старой колонки не переносятся: пользователям нужно залогиниться заново
+ALTER TABLE users DROP COLUMN refresh_token;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN refresh_token VARCHAR(300) UNIQUE;
+
+DROP TABLE IF EXISTS sessions;
+-- +goose StatementEnd
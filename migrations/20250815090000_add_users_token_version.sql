@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- версия токенов пользователя (claim ver): увеличивается при logout со всех устройств,
+-- токены с меньшей версией перестают приниматься
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS token_version;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- подтверждение email: новые пользователи неактивны до перехода по ссылке из письма,
+-- уже зарегистрированные пользователи считаются подтверждёнными
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET email_verified = TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified;
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+// Пакет migrations встраивает SQL-миграции (формат goose) в бинарник сервера
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// тест времени жизни записей кэша
+func TestCache_TTL(t *testing.T) {
+	now := time.Now()
+	c := New[int](time.Second, 10)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	now = now.Add(time.Second)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+
+	c.Set("b", 2)
+	c.Delete("b")
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+}
+
+// тест ограничения числа записей кэша
+func TestCache_MaxEntries(t *testing.T) {
+	now := time.Now()
+	c := New[int](time.Minute, 2)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	now = now.Add(time.Second)
+	c.Set("b", 2)
+	now = now.Add(time.Second)
+	c.Set("c", 3)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok, "запись с самым ранним истечением вытесняется")
+	_, ok = c.Get("b")
+	assert.True(t, ok)
+	_, ok = c.Get("c")
+	assert.True(t, ok)
+
+	// обновление существующей записи ничего не вытесняет
+	c.Set("c", 4)
+	value, _ := c.Get("c")
+	assert.Equal(t, 4, value)
+	_, ok = c.Get("b")
+	assert.True(t, ok)
+}
+
+// тест отключенного кэша
+func TestCache_Disabled(t *testing.T) {
+	c := New[int](0, 10)
+
+	c.Set("a", 1)
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+}
@@ -19,6 +19,7 @@ const (
 type PgRepoInterface interface {
 	Close()
 	GetPool() *pgxpool.Pool
+	Ping(ctx context.Context) error
 }
 
 type PgRepo struct {
@@ -77,3 +78,8 @@ func (r *PgRepo) Close() {
 func (r *PgRepo) GetPool() *pgxpool.Pool {
 	return r.pool
 }
+
+// Ping checks that the database is reachable (readiness probe)
+func (r *PgRepo) Ping(ctx context.Context) error {
+	return r.pool.Ping(ctx)
+}
@@ -0,0 +1,299 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ключ advisory lock, под которым миграции применяются только одним экземпляром сервера
+const migrationLockKey = 7_305_117_001
+
+// Одна миграция из файла вида <version>_<name>.sql с секциями "-- +goose Up" / "-- +goose Down"
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Состояние миграции для команды status
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // nil, если миграция не применена
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	log        *slog.Logger
+}
+
+// Конструктор мигратора, читает и разбирает все *.sql файлы из fsys
+func NewMigrator(pg PgRepoInterface, fsys fs.FS, log *slog.Logger) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		pool:       pg.GetPool(),
+		migrations: migrations,
+		log:        log,
+	}, nil
+}
+
+// Читает миграции из fsys, отсортированные по версии
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	seen := make(map[int64]string, len(files))
+	for _, file := range files {
+		versionStr, name, found := strings.Cut(strings.TrimSuffix(path.Base(file), ".sql"), "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s: file name must look like <version>_<name>.sql", file)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", file, err)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migration %s: duplicate version %d (also in %s)", file, version, other)
+		}
+		seen[version] = file
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		up, down, err := parseMigration(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", file, err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    name,
+			Up:      up,
+			Down:    down,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// разбирает файл миграции на секции Up и Down, убирая служебные комментарии goose
+func parseMigration(content string) (string, string, error) {
+	var up, down strings.Builder
+	var current *strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "-- +goose") {
+			switch strings.TrimSpace(strings.TrimPrefix(trimmed, "-- +goose")) {
+			case "Up":
+				current = &up
+			case "Down":
+				current = &down
+			}
+			// StatementBegin/StatementEnd не нужны: секция выполняется целиком одним запросом
+			continue
+		}
+
+		if current != nil {
+			current.WriteString(line)
+			current.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", err
+	}
+
+	if strings.TrimSpace(up.String()) == "" {
+		return "", "", errors.New("missing '-- +goose Up' section")
+	}
+
+	return strings.TrimSpace(up.String()), strings.TrimSpace(down.String()), nil
+}
+
+// Применяет все непримененные миграции по возрастанию версии
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]time.Time) error {
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+			}
+			m.log.Info("applied migration", "version", mig.Version, "name", mig.Name)
+		}
+		return nil
+	})
+}
+
+// Откатывает последнюю примененную миграцию
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+			}
+			m.log.Info("rolled back migration", "version", mig.Version, "name", mig.Name)
+			return nil
+		}
+		m.log.Info("no migrations to roll back")
+		return nil
+	})
+}
+
+// Возвращает состояние всех известных миграций
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *pgxpool.Conn, applied map[int64]time.Time) error {
+		for _, mig := range m.migrations {
+			st := MigrationStatus{Migration: mig}
+			if at, ok := applied[mig.Version]; ok {
+				st.AppliedAt = &at
+			}
+			statuses = append(statuses, st)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// выполняет SQL миграции и запись в schema_migrations в одной транзакции
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, sql, bookkeeping string, args ...interface{}) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if strings.TrimSpace(sql) != "" {
+		// без аргументов pgx использует simple protocol, секция может содержать несколько запросов
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// берёт отдельное соединение, advisory lock и список примененных версий, затем вызывает fn
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn, applied map[int64]time.Time) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		// контекст мог быть отменён, блокировку снимаем в любом случае
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			m.log.Error("failed to release migration lock", "error", err)
+		}
+	}()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// создаёт таблицу schema_migrations; если база ранее мигрировалась утилитой goose,
+// переносит из goose_db_version список уже применённых версий
+func ensureMigrationsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	const createQuery = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`
+	if _, err := conn.Exec(ctx, createQuery); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var gooseTable *string
+	if err := conn.QueryRow(ctx, `SELECT to_regclass('goose_db_version')::text`).Scan(&gooseTable); err != nil {
+		return fmt.Errorf("failed to check goose_db_version table: %w", err)
+	}
+	if gooseTable == nil {
+		return nil
+	}
+
+	// последняя запись по версии в goose_db_version определяет, применена ли миграция
+	const importQuery = `
+		INSERT INTO schema_migrations (version, name, applied_at)
+		SELECT version_id, '', tstamp
+		FROM (
+			SELECT DISTINCT ON (version_id) version_id, is_applied, tstamp
+			FROM goose_db_version
+			WHERE version_id > 0
+			ORDER BY version_id, id DESC
+		) g
+		WHERE g.is_applied AND NOT EXISTS (SELECT 1 FROM schema_migrations)
+		ON CONFLICT (version) DO NOTHING
+	`
+	if _, err := conn.Exec(ctx, importQuery); err != nil {
+		return fmt.Errorf("failed to import goose_db_version: %w", err)
+	}
+	return nil
+}
+
+// возвращает примененные версии и время их применения
+func appliedMigrations(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = at
+	}
+
+	return applied, rows.Err()
+}
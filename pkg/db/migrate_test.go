@@ -0,0 +1,59 @@
+package db
+
+import (
+	"simple_gin_server/migrations"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// тест разбора файлов миграций в формате goose
+func TestLoadMigrations(t *testing.T) {
+	t.Run("sections and ordering", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"20250102000000_second.sql": {Data: []byte("-- +goose Up\nCREATE TABLE b (id INT);\n-- +goose Down\nDROP TABLE b;\n")},
+			"20250101000000_first.sql": {Data: []byte(
+				"-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE a (id INT);\nCREATE INDEX idx_a ON a (id);\n-- +goose StatementEnd\n\n" +
+					"-- +goose Down\n-- +goose StatementBegin\nDROP TABLE a;\n-- +goose StatementEnd\n",
+			)},
+			"README.md": {Data: []byte("not a migration")},
+		}
+
+		migs, err := LoadMigrations(fsys)
+
+		require.NoError(t, err)
+		require.Len(t, migs, 2)
+		assert.Equal(t, int64(20250101000000), migs[0].Version)
+		assert.Equal(t, "first", migs[0].Name)
+		assert.Equal(t, "CREATE TABLE a (id INT);\nCREATE INDEX idx_a ON a (id);", migs[0].Up)
+		assert.Equal(t, "DROP TABLE a;", migs[0].Down)
+		assert.Equal(t, "second", migs[1].Name)
+	})
+
+	t.Run("invalid files", func(t *testing.T) {
+		_, err := LoadMigrations(fstest.MapFS{"first.sql": {Data: []byte("-- +goose Up\nSELECT 1;")}})
+		assert.Error(t, err)
+
+		_, err = LoadMigrations(fstest.MapFS{"1_first.sql": {Data: []byte("SELECT 1;")}})
+		assert.Error(t, err)
+
+		_, err = LoadMigrations(fstest.MapFS{
+			"1_first.sql":  {Data: []byte("-- +goose Up\nSELECT 1;")},
+			"01_again.sql": {Data: []byte("-- +goose Up\nSELECT 1;")},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("embedded migrations", func(t *testing.T) {
+		migs, err := LoadMigrations(migrations.FS)
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, migs)
+		for _, m := range migs {
+			assert.NotEmpty(t, m.Up, m.Name)
+			assert.NotEmpty(t, m.Down, m.Name)
+		}
+	})
+}
@@ -2,7 +2,8 @@ package db
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"simple_gin_server/configs"
 	"strconv"
 	"time"
@@ -10,19 +11,30 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// Ключ отсутствует в Redis (или истёк)
+var ErrKeyNotFound = errors.New("redis key not found")
+
 type ReddisRepoInterface interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Get(ctx context.Context, key string) (string, error)
+	GetDel(ctx context.Context, key string) (string, error)
 	Exists(ctx context.Context, redisKey string) (bool, error)
+	Del(ctx context.Context, keys ...string) error
+	Incr(ctx context.Context, key string) (int64, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	CompareAndSet(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error)
 }
 
 type RedisRepo struct {
 	client *redis.Client
+	log    *slog.Logger
 }
 
-func NewRedisRepo(ctx context.Context, conf *configs.Config) *RedisRepo {
+func NewRedisRepo(ctx context.Context, conf *configs.Config, log *slog.Logger) *RedisRepo {
 	reddis_db_n, err := strconv.Atoi(conf.Redis.NDB)
 	if err != nil {
-		log.Println("Could not read reddis DB number from config")
+		log.Warn("could not read redis DB number from config, using 0", "db", conf.Redis.NDB)
 	}
 	return &RedisRepo{
 		redis.NewClient(
@@ -32,20 +44,104 @@ func NewRedisRepo(ctx context.Context, conf *configs.Config) *RedisRepo {
 				DB:       reddis_db_n,
 			},
 		),
+		log,
 	}
 }
 
+// Клиент Redis для операций вне репозитория (Lua скрипты ограничения частоты запросов)
+func (r *RedisRepo) Client() *redis.Client {
+	return r.client
+}
+
+// Проверка доступности Redis (readiness probe)
+func (r *RedisRepo) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Закрытие соединений с Redis при остановке сервиса
+func (r *RedisRepo) Close() error {
+	return r.client.Close()
+}
+
 func (r *RedisRepo) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	return r.client.Set(ctx, key, value, expiration).Err()
 }
 
+// Получение значения по ключу, если ключа нет - ErrKeyNotFound
+func (r *RedisRepo) Get(ctx context.Context, key string) (string, error) {
+	value, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrKeyNotFound
+	}
+	return value, err
+}
+
+// Атомарное получение и удаление значения (одноразовые токены), если ключа нет - ErrKeyNotFound
+func (r *RedisRepo) GetDel(ctx context.Context, key string) (string, error) {
+	value, err := r.client.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrKeyNotFound
+	}
+	return value, err
+}
+
 func (r *RedisRepo) Exists(ctx context.Context, redisKey string) (bool, error) {
 	result, err := r.client.Exists(ctx, redisKey).Result()
 	if err != nil {
-		log.Printf("[redis_repo.go]---[Exists()], Err:%v", err)
+		r.log.ErrorContext(ctx, "redis exists failed", "key", redisKey, "error", err)
 		return false, err
 	}
 
 	// Возвращает true, если ключ существует (1), false если нет (0)
 	return result == 1, nil
 }
+
+// Удаление ключей, отсутствующие ключи пропускаются
+func (r *RedisRepo) Del(ctx context.Context, keys ...string) error {
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// Увеличение счётчика на 1 (отсутствующий ключ считается равным 0), возвращает новое значение
+func (r *RedisRepo) Incr(ctx context.Context, key string) (int64, error) {
+	return r.client.Incr(ctx, key).Result()
+}
+
+// Установка времени жизни ключа
+func (r *RedisRepo) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return r.client.Expire(ctx, key, expiration).Err()
+}
+
+// Запись value, только если текущее значение ключа равно old ("" - ключа нет), одним скриптом
+var compareAndSetScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if (current or '') ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// Атомарная запись value, если значение ключа не изменилось с момента чтения (old, "" - ключа не было).
+// Возвращает false, если ключ успели изменить: значение, вычисленное по старым данным, не записывается
+func (r *RedisRepo) CompareAndSet(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error) {
+	set, err := compareAndSetScript.Run(ctx, r.client, []string{key}, old, value, expiration.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return set == 1, nil
+}
+
+// Оставшееся время жизни ключа, если ключа нет - ErrKeyNotFound, у ключа без срока жизни - 0
+func (r *RedisRepo) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case ttl == -2:
+		return 0, ErrKeyNotFound
+	case ttl < 0:
+		return 0, nil
+	}
+	return ttl, nil
+}
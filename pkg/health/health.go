@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Проверка зависимости (Postgres, Redis): nil - зависимость доступна
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Статус одной зависимости в ответе /readyz
+type CheckStatus struct {
+	Status    string `json:"status"` // "up" или "down"
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"` // "timeout" или "unavailable", подробности только в логе
+}
+
+// Ответ /readyz
+type ReadinessResponse struct {
+	Status string                 `json:"status"` // "ready", "not_ready" или "draining"
+	Checks map[string]CheckStatus `json:"checks,omitempty"`
+}
+
+// Liveness и readiness пробы (Kubernetes, балансировщик). Liveness отвечает, пока процесс жив,
+// readiness проверяет зависимости и отвечает 503 во время остановки, чтобы трафик успел уйти на другие экземпляры
+type Health struct {
+	timeout  time.Duration // таймаут каждой проверки
+	checks   []check
+	draining atomic.Bool
+	log      *slog.Logger
+}
+
+func New(timeout time.Duration, log *slog.Logger) *Health {
+	return &Health{timeout: timeout, log: log}
+}
+
+// Регистрация проверки зависимости (до запуска сервера)
+func (h *Health) Add(name string, fn CheckFunc) {
+	h.checks = append(h.checks, check{name: name, fn: fn})
+}
+
+// Перевод в состояние остановки: readiness отвечает 503 независимо от зависимостей
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+// GET /livez: процесс жив и обрабатывает запросы, зависимости не проверяются
+// (их недоступность не лечится перезапуском)
+func (h *Health) LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GET /readyz: все зависимости доступны - 200, иначе 503 со статусом каждой зависимости
+func (h *Health) ReadinessHandler(c *gin.Context) {
+	if h.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, ReadinessResponse{Status: "draining"})
+		return
+	}
+
+	resp := h.Check(c.Request.Context())
+	status := http.StatusOK
+	if resp.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, resp)
+}
+
+// Параллельная проверка всех зависимостей, каждая со своим таймаутом
+func (h *Health) Check(ctx context.Context) ReadinessResponse {
+	statuses := make([]CheckStatus, len(h.checks))
+
+	var wg sync.WaitGroup
+	for i, chk := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = h.run(ctx, chk.name, chk.fn)
+		}()
+	}
+	wg.Wait()
+
+	resp := ReadinessResponse{Status: "ready", Checks: make(map[string]CheckStatus, len(h.checks))}
+	for i, chk := range h.checks {
+		resp.Checks[chk.name] = statuses[i]
+		if statuses[i].Status != "up" {
+			resp.Status = "not_ready"
+		}
+	}
+	return resp
+}
+
+func (h *Health) run(ctx context.Context, name string, fn CheckFunc) CheckStatus {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	status := CheckStatus{Status: "up", LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		status.Status = "down"
+		status.Error = "unavailable"
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			status.Error = "timeout"
+		}
+		h.log.WarnContext(ctx, "readiness check failed", "check", name, "error", err)
+	}
+	return status
+}
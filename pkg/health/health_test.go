@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"simple_gin_server/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readiness(t *testing.T, h *Health) (int, ReadinessResponse) {
+	router := gin.New()
+	router.GET("/readyz", h.ReadinessHandler)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	var resp ReadinessResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+// тест readiness: статус каждой зависимости, зависшая проверка прерывается по таймауту
+func TestReadinessHandler(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	refused := func(ctx context.Context) error { return errors.New("dial tcp 10.0.0.5:6379: connection refused") }
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	h := New(20*time.Millisecond, logger.Nop())
+	h.Add("postgres", up)
+	code, resp := readiness(t, h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", resp.Status)
+	assert.Equal(t, "up", resp.Checks["postgres"].Status)
+
+	h.Add("redis", refused)
+	h.Add("search", hanging)
+	code, resp = readiness(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", resp.Status)
+	assert.Equal(t, "up", resp.Checks["postgres"].Status)
+	assert.Equal(t, CheckStatus{Status: "down", Error: "unavailable", LatencyMs: resp.Checks["redis"].LatencyMs}, resp.Checks["redis"])
+	assert.Equal(t, "timeout", resp.Checks["search"].Error)
+}
+
+// тест остановки: readiness отвечает 503 без проверки зависимостей, liveness - 200
+func TestHealth_Draining(t *testing.T) {
+	h := New(time.Second, logger.Nop())
+	h.Add("postgres", func(ctx context.Context) error {
+		t.Error("зависимости не проверяются во время остановки")
+		return nil
+	})
+	h.SetDraining()
+
+	code, resp := readiness(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ReadinessResponse{Status: "draining"}, resp)
+
+	router := gin.New()
+	router.GET("/livez", h.LivenessHandler)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/livez", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
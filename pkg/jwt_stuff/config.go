@@ -0,0 +1,91 @@
+package jwt_stuff
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"simple_gin_server/configs"
+	"strings"
+)
+
+// Создание JWT по конфигу: access токены подписываются ключом conf.SigningAlg, refresh токены - секретом HS256.
+// При переходе с HS256 на асимметричный алгоритм секрет JWT_ACC_SECRET (если задан) остаётся ключом проверки
+// токенов без kid, чтобы уже выданные access токены принимались до своего истечения
+func NewJWTFromConfig(conf configs.AuthConfig) (*JWT, error) {
+	accessKeys, err := loadAccessKeys(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshKeys, err := NewKeyRing(NewHMACKey("", []byte(conf.SecretRef)))
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := NewJWTWithKeys(accessKeys, refreshKeys, conf.AccessTokenExp, conf.RefreshTokenExp)
+	if conf.Issuer != "" {
+		tokens.Issuer = conf.Issuer
+	}
+	tokens.Audience = conf.Audience
+	return tokens, nil
+}
+
+// собирает набор ключей access токенов из конфига
+func loadAccessKeys(conf configs.AuthConfig) (*KeyRing, error) {
+	var verifyOnly []*Key
+	for _, item := range conf.VerifyKeyFiles {
+		kid, path, found := strings.Cut(item, "=")
+		if !found || kid == "" || path == "" {
+			return nil, fmt.Errorf("invalid verify key %q, expected kid=path", item)
+		}
+		key, err := readPEMKey(kid, path)
+		if err != nil {
+			return nil, err
+		}
+		verifyOnly = append(verifyOnly, key)
+	}
+
+	if conf.SigningAlg == AlgHS256 {
+		return NewKeyRing(NewHMACKey(conf.KeyID, []byte(conf.SecretAcc)), verifyOnly...)
+	}
+
+	if conf.KeyID == "" {
+		return nil, fmt.Errorf("key id is required for %s signing", conf.SigningAlg)
+	}
+	if conf.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("private key file is required for %s signing", conf.SigningAlg)
+	}
+
+	pemBytes, err := os.ReadFile(conf.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	signing, err := ParsePEMKey(conf.KeyID, conf.SigningAlg, pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !signing.CanSign() {
+		return nil, errors.New("private key file contains a public key")
+	}
+
+	if conf.SecretAcc != "" {
+		verifyOnly = append(verifyOnly, NewHMACKey("", []byte(conf.SecretAcc)))
+	}
+
+	return NewKeyRing(signing, verifyOnly...)
+}
+
+// читает PEM ключ проверки, алгоритм (RS256 или EdDSA) определяется по типу ключа
+func readPEMKey(kid, path string) (*Key, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %q: %w", kid, err)
+	}
+
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		if key, err := ParsePEMKey(kid, alg, pemBytes); err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("key %q is neither RSA nor Ed25519 PEM key", kid)
+}
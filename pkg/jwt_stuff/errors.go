@@ -0,0 +1,16 @@
+package jwt_stuff
+
+import "errors"
+
+// Ошибки проверки токена, по ним транспортный слой выбирает ответ
+var (
+	ErrTokenMalformed   = errors.New("token is malformed")
+	ErrTokenExpired     = errors.New("token is expired")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrInvalidSignature = errors.New("token signature is invalid")
+	ErrWrongTokenType   = errors.New("wrong token type")
+	ErrMissingClaims    = errors.New("token is missing required claims")
+	ErrInvalidIssuer    = errors.New("token has invalid issuer")
+	ErrInvalidAudience  = errors.New("token has invalid audience")
+	ErrTokenInvalid     = errors.New("token is invalid")
+)
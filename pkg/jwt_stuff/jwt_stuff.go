@@ -3,38 +3,72 @@ package jwt_stuff
 import (
 	"errors"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// Издатель токенов по умолчанию
+const DefaultIssuer = "my_app"
+
+// Тип токена подтверждения email
+const TokenTypeEmailVerify = "email_verify"
+
+// Конструктор JWT с подписью HS256 общими секретами (токены без kid)
 func NewJWT(secretAcc string, secretRef string, accessTokenExp, refreshTokenExp time.Duration) *JWT {
+	accessKeys, _ := NewKeyRing(NewHMACKey("", []byte(secretAcc)))
+	refreshKeys, _ := NewKeyRing(NewHMACKey("", []byte(secretRef)))
+	return NewJWTWithKeys(accessKeys, refreshKeys, accessTokenExp, refreshTokenExp)
+}
+
+// Конструктор JWT с произвольными наборами ключей (RS256, EdDSA, ротация ключей)
+func NewJWTWithKeys(accessKeys, refreshKeys *KeyRing, accessTokenExp, refreshTokenExp time.Duration) *JWT {
 	return &JWT{
-		SecretAccKey:    secretAcc,
-		SecretRefKey:    secretRef,
+		AccessKeys:      accessKeys,
+		RefreshKeys:     refreshKeys,
 		AccessTokenExp:  accessTokenExp,
 		RefreshTokenExp: refreshTokenExp,
+		Issuer:          DefaultIssuer,
 	}
 }
 
-func (j *JWT) GenerateTokens(email, userId string) (string, string, error) {
+// Проверка access токенов (подпись, срок действия, iss и aud)
+func (j *JWT) AccessVerifier() *Verifier {
+	return NewVerifier(j.AccessKeys, "access", j.Issuer, j.Audience)
+}
+
+// Проверка refresh токенов (подпись, срок действия, iss и aud)
+func (j *JWT) RefreshVerifier() *Verifier {
+	return NewVerifier(j.RefreshKeys, "refresh", j.Issuer, j.Audience)
+}
+
+// Генерация пары access и refresh токенов, в claims кладутся id, роль, признак активности и версия токенов пользователя.
+// Refresh токен начинает новое семейство токенов (новый логин)
+func (j *JWT) GenerateTokens(email, userId, role string, isActive bool, version int) (string, string, error) {
+	return j.GenerateTokensInFamily(email, userId, role, isActive, version, uuid.New().String())
+}
+
+// Генерация пары токенов при ротации: новый refresh токен остаётся в семействе familyId
+func (j *JWT) GenerateTokensInFamily(email, userId, role string, isActive bool, version int, familyId string) (string, string, error) {
 
 	// Access токен
-	accessClaims := NewClaims(j.AccessTokenExp, email, userId, "access", "my_app")
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(j.SecretAccKey))
+	accessClaims := NewClaims(j.AccessTokenExp, email, userId, role, isActive, "access", j.Issuer)
+	accessClaims.FamilyId = familyId // id сессии, нужен для действий над текущей сессией (смена пароля)
+	accessClaims.Version = version
+	j.setAudience(&accessClaims)
+	accessTokenString, err := j.AccessKeys.Sign(accessClaims)
 	if err != nil {
 		return "", "", err
 	}
 
 	// Refresh токен
-	refreshClaims := NewClaims(j.RefreshTokenExp, email, userId, "refresh", "my_app")
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(j.SecretRefKey))
+	refreshClaims := NewClaims(j.RefreshTokenExp, email, userId, role, isActive, "refresh", j.Issuer)
+	refreshClaims.FamilyId = familyId
+	refreshClaims.Version = version
+	j.setAudience(&refreshClaims)
+	refreshTokenString, err := j.RefreshKeys.Sign(refreshClaims)
 	if err != nil {
 		return "", "", err
 	}
@@ -42,11 +76,38 @@ func (j *JWT) GenerateTokens(email, userId string) (string, string, error) {
 	return accessTokenString, refreshTokenString, nil
 }
 
-func NewClaims(TokenExp time.Duration, email, userId, tokenType, issuer string) CustomClaims {
+// Генерация токена подтверждения email для ссылки из письма. Подписывается ключом refresh токенов
+// (проверяется только этим сервисом). Возвращает токен и его jti
+func (j *JWT) GenerateVerificationToken(email, userId string, exp time.Duration) (string, string, error) {
+	claims := NewClaims(exp, email, userId, "", false, TokenTypeEmailVerify, j.Issuer)
+	j.setAudience(&claims)
+
+	token, err := j.RefreshKeys.Sign(claims)
+	if err != nil {
+		return "", "", err
+	}
+	return token, claims.ID, nil
+}
+
+// Проверка токенов подтверждения email
+func (j *JWT) VerificationVerifier() *Verifier {
+	return NewVerifier(j.RefreshKeys, TokenTypeEmailVerify, j.Issuer, j.Audience)
+}
+
+// добавляет в claims аудиторию, если она задана
+func (j *JWT) setAudience(claims *CustomClaims) {
+	if j.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{j.Audience}
+	}
+}
+
+func NewClaims(TokenExp time.Duration, email, userId, role string, isActive bool, tokenType, issuer string) CustomClaims {
 	newClaim := CustomClaims{
 		Email:     email,
 		TokenType: tokenType,
+		Role:      role,
 		UserId:    userId,
+		IsActive:  isActive,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExp)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -57,40 +118,6 @@ func NewClaims(TokenExp time.Duration, email, userId, tokenType, issuer string)
 	return newClaim
 }
 
-func ParseTokenWithClaims(c *gin.Context, tokenString string, key string) (*jwt.Token, error) {
-	// Проверяем не отменен ли контекст
-	if err := c.Err(); err != nil {
-		return nil, err
-	}
-
-	//создаём новый парсер, который учитываем метод шифрования и подтверждение срока действия
-	parser := jwt.NewParser(
-		jwt.WithValidMethods([]string{"HS256"}),
-		jwt.WithExpirationRequired(),
-	)
-
-	// пытаемся получить токен
-	token, err := parser.ParseWithClaims(
-		tokenString,
-		&CustomClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(key), nil
-		})
-
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"error":   "Invalid token",
-			"details": err.Error(),
-		})
-		return nil, err
-	}
-
-	return token, nil
-}
-
 // parseTokenWithoutVerification парсит JWT токен без проверки подписи,
 // но с проверкой базовой структуры и обязательных полей
 func ParseTokenWithoutVerification(tokenString string) (*CustomClaims, error) {
@@ -106,7 +133,7 @@ func ParseTokenWithoutVerification(tokenString string) (*CustomClaims, error) {
 
 	// создаём новый парсер без валидации клэймов
 	parser := jwt.NewParser(
-		jwt.WithValidMethods([]string{"HS256"}),
+		jwt.WithValidMethods(supportedAlgs),
 		jwt.WithoutClaimsValidation(),
 	)
 
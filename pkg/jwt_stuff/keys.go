@@ -0,0 +1,243 @@
+package jwt_stuff
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Алгоритмы подписи токенов
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// все алгоритмы, которые могут встретиться в токенах сервиса
+var supportedAlgs = []string{AlgHS256, AlgRS256, AlgEdDSA}
+
+// Ключ подписи и проверки токенов. Ключ без приватной части используется только для проверки
+// (например, предыдущий ключ после ротации, пока не истекут подписанные им токены)
+type Key struct {
+	ID      string // kid в заголовке токена, "" - токены без kid (выданные до появления ключей)
+	Method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// Симметричный ключ HS256 (секрет нужен и для подписи, и для проверки, в JWKS не публикуется)
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, private: secret, public: secret}
+}
+
+// Ключ RS256 для подписи
+func NewRSAKey(id string, private *rsa.PrivateKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodRS256, private: private, public: &private.PublicKey}
+}
+
+// Ключ RS256 только для проверки
+func NewRSAPublicKey(id string, public *rsa.PublicKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodRS256, public: public}
+}
+
+// Ключ EdDSA (Ed25519) для подписи
+func NewEd25519Key(id string, private ed25519.PrivateKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodEdDSA, private: private, public: private.Public()}
+}
+
+// Ключ EdDSA (Ed25519) только для проверки
+func NewEd25519PublicKey(id string, public ed25519.PublicKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodEdDSA, public: public}
+}
+
+// Разбор ключа alg (RS256 или EdDSA) из PEM: приватный ключ даёт ключ подписи, публичный - ключ только для проверки
+func ParsePEMKey(id, alg string, pemBytes []byte) (*Key, error) {
+	switch alg {
+	case AlgRS256:
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+			return NewRSAKey(id, private), nil
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA key %q: %w", id, err)
+		}
+		return NewRSAPublicKey(id, public), nil
+	case AlgEdDSA:
+		if private, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes); err == nil {
+			return NewEd25519Key(id, private.(ed25519.PrivateKey)), nil
+		}
+		public, err := jwt.ParseEdPublicKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Ed25519 key %q: %w", id, err)
+		}
+		return NewEd25519PublicKey(id, public.(ed25519.PublicKey)), nil
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", alg)
+	}
+}
+
+// Можно ли подписывать этим ключом
+func (k *Key) CanSign() bool {
+	return k.private != nil
+}
+
+// Набор ключей одного типа токенов: один ключ подписи и любое число ключей проверки.
+// Ротация: новый ключ становится ключом подписи, предыдущий остаётся для проверки уже выданных токенов
+type KeyRing struct {
+	mu      sync.RWMutex
+	signing *Key
+	keys    map[string]*Key
+}
+
+// Конструктор набора ключей, verifyOnly - ключи, которые принимаются при проверке, но не используются для подписи
+func NewKeyRing(signing *Key, verifyOnly ...*Key) (*KeyRing, error) {
+	r := &KeyRing{keys: make(map[string]*Key)}
+	for _, key := range verifyOnly {
+		r.keys[key.ID] = key
+	}
+	if err := r.Rotate(signing); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Делает key ключом подписи, прежний ключ подписи остаётся в наборе для проверки
+func (r *KeyRing) Rotate(key *Key) error {
+	if key == nil || !key.CanSign() {
+		return errors.New("signing key must have a private part")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[key.ID] = key
+	r.signing = key
+	return nil
+}
+
+// Удаляет ключ проверки (токены, подписанные им, перестают приниматься). Ключ подписи удалить нельзя
+func (r *KeyRing) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.signing.ID == id {
+		return errors.New("can not remove current signing key")
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+// Подписывает claims текущим ключом подписи, kid ключа записывается в заголовок токена
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	r.mu.RLock()
+	key := r.signing
+	r.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.private)
+}
+
+// Алгоритмы, которые принимаются при проверке токенов этого набора
+func (r *KeyRing) Methods() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var methods []string
+	for _, key := range r.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// jwt.Keyfunc: ключ проверки выбирается по kid из заголовка, алгоритм токена должен совпадать с алгоритмом ключа
+func (r *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	r.mu.RLock()
+	key, ok := r.keys[kid]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// Публичный ключ в формате JWK (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // кривая OKP ключа
+	X   string `json:"x,omitempty"`   // публичный ключ Ed25519
+}
+
+// Набор публичных ключей для /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Публичные ключи набора (симметричные ключи не публикуются)
+func (r *KeyRing) JWKS() JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.keys {
+		if jwk, ok := key.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// представление публичной части ключа в формате JWK
+func (k *Key) jwk() (JWK, bool) {
+	enc := base64.RawURLEncoding
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			N:   enc.EncodeToString(public.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Crv: "Ed25519",
+			X:   enc.EncodeToString(public),
+		}, true
+	default:
+		// симметричный ключ
+		return JWK{}, false
+	}
+}
@@ -0,0 +1,175 @@
+package jwt_stuff
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"simple_gin_server/configs"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// проверяет access токен так же, как AuthMiddleware
+func parseWith(t *testing.T, keys *KeyRing, token string) (*CustomClaims, error) {
+	return NewVerifier(keys, "access", DefaultIssuer, "").Verify(context.Background(), token)
+}
+
+// kid из заголовка токена
+func headerKid(t *testing.T, token string) any {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &CustomClaims{})
+	require.NoError(t, err)
+	return parsed.Header["kid"]
+}
+
+func newTestRSAKey(t *testing.T, kid string) *Key {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return NewRSAKey(kid, private)
+}
+
+func newTestEd25519Key(t *testing.T, kid string) *Key {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return NewEd25519Key(kid, private)
+}
+
+// тест подписи и проверки токенов асимметричными ключами, kid в заголовке
+func TestKeyRing_SignAndVerify(t *testing.T) {
+	for _, key := range []*Key{newTestRSAKey(t, "rsa-1"), newTestEd25519Key(t, "ed-1")} {
+		t.Run(key.Method.Alg(), func(t *testing.T) {
+			keys, err := NewKeyRing(key)
+			require.NoError(t, err)
+
+			tokens := NewJWTWithKeys(keys, keys, time.Minute, time.Hour)
+			access, _, err := tokens.GenerateTokens("test@example.com", "5", "user", true, 0)
+			require.NoError(t, err)
+
+			claims, err := parseWith(t, keys, access)
+			require.NoError(t, err)
+			assert.Equal(t, key.ID, headerKid(t, access))
+			assert.Equal(t, "5", claims.UserId)
+		})
+	}
+}
+
+// тест ротации: токены прежнего ключа принимаются, пока ключ не удалён из набора
+func TestKeyRing_Rotation(t *testing.T) {
+	oldKey := newTestRSAKey(t, "key-1")
+	keys, err := NewKeyRing(oldKey)
+	require.NoError(t, err)
+
+	tokens := NewJWTWithKeys(keys, keys, time.Minute, time.Hour)
+	oldToken, _, err := tokens.GenerateTokens("test@example.com", "5", "user", true, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, keys.Rotate(newTestEd25519Key(t, "key-2")))
+	newToken, _, err := tokens.GenerateTokens("test@example.com", "5", "user", true, 0)
+	require.NoError(t, err)
+
+	_, err = parseWith(t, keys, newToken)
+	require.NoError(t, err)
+	assert.Equal(t, "key-2", headerKid(t, newToken))
+
+	_, err = parseWith(t, keys, oldToken)
+	assert.NoError(t, err)
+
+	assert.Error(t, keys.Remove("key-2"), "ключ подписи удалить нельзя")
+	require.NoError(t, keys.Remove("key-1"))
+	_, err = parseWith(t, keys, oldToken)
+	assert.Error(t, err)
+}
+
+// тест защиты от подмены алгоритма: HS256 токен, подписанный опубликованным публичным ключом как секретом, не принимается
+func TestKeyRing_AlgorithmConfusion(t *testing.T) {
+	edKey := newTestEd25519Key(t, "ed-1")
+	public := edKey.public.(ed25519.PublicKey)
+	keys, err := NewKeyRing(edKey, NewHMACKey("", []byte("secret")))
+	require.NoError(t, err)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, NewClaims(time.Minute, "test@example.com", "5", "admin", true, "access", "my_app"))
+	forged.Header["kid"] = "ed-1"
+	forgedString, err := forged.SignedString([]byte(public))
+	require.NoError(t, err)
+
+	_, err = parseWith(t, keys, forgedString)
+	assert.Error(t, err)
+
+	// токены без kid проверяются симметричным ключом с пустым kid
+	legacy, _, err := NewJWT("secret", "ref", time.Minute, time.Hour).GenerateTokens("test@example.com", "5", "user", true, 0)
+	require.NoError(t, err)
+	_, err = parseWith(t, keys, legacy)
+	assert.NoError(t, err)
+}
+
+// тест публикации ключей в JWKS: симметричные ключи не публикуются
+func TestKeyRing_JWKS(t *testing.T) {
+	keys, err := NewKeyRing(newTestEd25519Key(t, "ed-1"), newTestRSAKey(t, "rsa-1"), NewHMACKey("", []byte("secret")))
+	require.NoError(t, err)
+
+	set := keys.JWKS()
+
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, JWK{Kty: "OKP", Kid: "ed-1", Use: "sig", Alg: AlgEdDSA, Crv: "Ed25519", X: set.Keys[0].X}, set.Keys[0])
+	assert.Equal(t, "RSA", set.Keys[1].Kty)
+	assert.Equal(t, "rsa-1", set.Keys[1].Kid)
+	assert.Equal(t, "AQAB", set.Keys[1].E)
+	assert.NotEmpty(t, set.Keys[1].N)
+}
+
+// тест загрузки ключей из конфига: EdDSA ключ подписи, ключ проверки после ротации и секрет для токенов без kid
+func TestNewJWTFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+		return path
+	}
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	require.NoError(t, err)
+
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaPrivate.PublicKey)
+	require.NoError(t, err)
+
+	conf := configs.AuthConfig{
+		SecretAcc:       "acc-secret",
+		SecretRef:       "ref-secret",
+		SigningAlg:      AlgEdDSA,
+		KeyID:           "ed-2",
+		PrivateKeyFile:  writePEM("ed.pem", "PRIVATE KEY", edDER),
+		VerifyKeyFiles:  []string{"rsa-1=" + writePEM("rsa.pub.pem", "PUBLIC KEY", rsaDER)},
+		AccessTokenExp:  time.Minute,
+		RefreshTokenExp: time.Hour,
+	}
+
+	tokens, err := NewJWTFromConfig(conf)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{AlgEdDSA, AlgHS256, AlgRS256}, tokens.AccessKeys.Methods())
+	assert.Len(t, tokens.AccessKeys.JWKS().Keys, 2)
+
+	access, refresh, err := tokens.GenerateTokens("test@example.com", "5", "user", true, 0)
+	require.NoError(t, err)
+	_, err = tokens.AccessVerifier().Verify(context.Background(), access)
+	require.NoError(t, err)
+	assert.Equal(t, "ed-2", headerKid(t, access))
+	_, err = tokens.RefreshVerifier().Verify(context.Background(), refresh)
+	assert.NoError(t, err)
+
+	conf.KeyID = ""
+	_, err = NewJWTFromConfig(conf)
+	assert.Error(t, err, "kid обязателен для асимметричного ключа")
+}
@@ -8,10 +8,12 @@ import (
 
 // Конфигурация JWT
 type JWT struct {
-	SecretAccKey    string
-	SecretRefKey    string
+	AccessKeys      *KeyRing // ключи access токенов (публичные ключи доступны другим сервисам через JWKS)
+	RefreshKeys     *KeyRing // ключи refresh токенов (проверяются только этим сервисом)
 	AccessTokenExp  time.Duration
 	RefreshTokenExp time.Duration
+	Issuer          string // iss выдаваемых токенов, проверяется при проверке токенов
+	Audience        string // aud выдаваемых токенов, "" - без аудитории и без её проверки
 }
 
 // Claims для JWT
@@ -21,5 +23,7 @@ type CustomClaims struct {
 	Role      string `json:"role"`
 	UserId    string `json:"user_id"` // userID для извлечения из JWT токена
 	IsActive  bool   `json:"is_active"`
+	FamilyId  string `json:"fid,omitempty"` // семейство refresh токенов: общее для всех токенов, полученных ротацией от одного логина
+	Version   int    `json:"ver"`           // версия токенов пользователя: при logout со всех устройств увеличивается, старые токены перестают приниматься
 	jwt.RegisteredClaims
 }
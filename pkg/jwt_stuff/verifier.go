@@ -0,0 +1,95 @@
+package jwt_stuff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Проверка токенов одного типа, не зависит от транспорта (HTTP, gRPC, WebSocket, фоновые задачи).
+// Возвращает claims или одну из ошибок errors.go (обёрнутую с подробностями)
+type Verifier struct {
+	keys      *KeyRing
+	tokenType string // "access" или "refresh"
+	issuer    string // "" - iss не проверяется
+	audience  string // "" - aud не проверяется
+}
+
+// Конструктор проверки токенов типа tokenType, подписанных ключами keys
+func NewVerifier(keys *KeyRing, tokenType, issuer, audience string) *Verifier {
+	return &Verifier{
+		keys:      keys,
+		tokenType: tokenType,
+		issuer:    issuer,
+		audience:  audience,
+	}
+}
+
+// Проверка подписи (ключ выбирается по kid), срока действия, издателя, аудитории, типа токена и обязательных claims
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*CustomClaims, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(v.keys.Methods()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if v.issuer != "" {
+		options = append(options, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		options = append(options, jwt.WithAudience(v.audience))
+	}
+
+	token, err := jwt.NewParser(options...).ParseWithClaims(tokenString, &CustomClaims{}, v.keys.Keyfunc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", classifyParseError(err), err)
+	}
+
+	claims, ok := token.Claims.(*CustomClaims)
+	if !ok || !token.Valid {
+		return nil, ErrTokenInvalid
+	}
+
+	if claims.TokenType != v.tokenType {
+		return nil, fmt.Errorf("%w: expected %s token, got %q", ErrWrongTokenType, v.tokenType, claims.TokenType)
+	}
+
+	switch {
+	case claims.ID == "":
+		return nil, fmt.Errorf("%w: jti", ErrMissingClaims)
+	case claims.Email == "":
+		return nil, fmt.Errorf("%w: email", ErrMissingClaims)
+	case claims.UserId == "":
+		return nil, fmt.Errorf("%w: user_id", ErrMissingClaims)
+	}
+
+	return claims, nil
+}
+
+// сопоставляет ошибку библиотеки jwt с ошибкой пакета
+func classifyParseError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return ErrTokenMalformed
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotYetValid
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrInvalidSignature
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrInvalidIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrInvalidAudience
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return ErrMissingClaims
+	default:
+		return ErrTokenInvalid
+	}
+}
@@ -0,0 +1,86 @@
+package jwt_stuff
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// тест проверки токенов: каждая причина отказа возвращает свою ошибку
+func TestVerifier_Verify(t *testing.T) {
+	tokens := NewJWT("acc-secret", "ref-secret", time.Minute, time.Hour)
+	tokens.Audience = "wisp-api"
+	access, refresh, err := tokens.GenerateTokens("test@example.com", "5", "user", true, 0)
+	require.NoError(t, err)
+
+	sign := func(claims CustomClaims) string {
+		token, err := tokens.AccessKeys.Sign(claims)
+		require.NoError(t, err)
+		return token
+	}
+
+	expired := NewClaims(-time.Minute, "test@example.com", "5", "user", true, "access", DefaultIssuer)
+	expired.Audience = jwt.ClaimStrings{"wisp-api"}
+	noEmail := NewClaims(time.Minute, "", "5", "user", true, "access", DefaultIssuer)
+	noEmail.Audience = jwt.ClaimStrings{"wisp-api"}
+	otherIssuer := NewClaims(time.Minute, "test@example.com", "5", "user", true, "access", "other_app")
+	otherIssuer.Audience = jwt.ClaimStrings{"wisp-api"}
+	otherAudience := NewClaims(time.Minute, "test@example.com", "5", "user", true, "access", DefaultIssuer)
+	otherAudience.Audience = jwt.ClaimStrings{"other-api"}
+
+	foreign := NewJWT("other-secret", "ref-secret", time.Minute, time.Hour)
+	foreign.Audience = "wisp-api"
+	forged, _, err := foreign.GenerateTokens("test@example.com", "5", "admin", true, 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid token", token: access},
+		{name: "malformed token", token: "not-a-token", wantErr: ErrTokenMalformed},
+		{name: "expired token", token: sign(expired), wantErr: ErrTokenExpired},
+		{name: "bad signature", token: forged, wantErr: ErrInvalidSignature},
+		{name: "refresh token signed by refresh key", token: refresh, wantErr: ErrInvalidSignature},
+		{name: "missing claims", token: sign(noEmail), wantErr: ErrMissingClaims},
+		{name: "wrong issuer", token: sign(otherIssuer), wantErr: ErrInvalidIssuer},
+		{name: "wrong audience", token: sign(otherAudience), wantErr: ErrInvalidAudience},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tokens.AccessVerifier().Verify(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, claims)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "5", claims.UserId)
+		})
+	}
+}
+
+// тест проверки типа токена: refresh токен, подписанный ключом access токенов, не принимается как access
+func TestVerifier_WrongTokenType(t *testing.T) {
+	keys, err := NewKeyRing(NewHMACKey("", []byte("secret")))
+	require.NoError(t, err)
+	tokens := NewJWTWithKeys(keys, keys, time.Minute, time.Hour)
+	access, refresh, err := tokens.GenerateTokens("test@example.com", "5", "user", true, 0)
+	require.NoError(t, err)
+
+	_, err = tokens.AccessVerifier().Verify(context.Background(), refresh)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+	_, err = tokens.RefreshVerifier().Verify(context.Background(), access)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = tokens.AccessVerifier().Verify(ctx, access)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"simple_gin_server/configs"
+)
+
+// Имена полей запроса в логах
+const (
+	KeyRequestID = "request_id"
+	KeyUserID    = "user_id"
+	KeyRoute     = "route"
+)
+
+// Форматы вывода
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Логгер по конфигу: текстовый или JSON вывод в w, к каждой записи добавляются поля запроса из контекста (WithAttrs)
+func New(conf configs.LogConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(conf.Level)); err != nil {
+		return nil, fmt.Errorf("log level %q: %w", conf.Level, err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch conf.Format {
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", conf.Format)
+	}
+	return slog.New(&ContextHandler{Handler: handler}), nil
+}
+
+// Логгер, отбрасывающий все записи (тесты)
+func Nop() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+type ctxKey struct{}
+
+// Контекст с дополнительными полями для всех записей лога, сделанных с этим контекстом
+// (request id, id пользователя, маршрут). Повторное поле с тем же именем заменяет прежнее
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev := attrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	for _, a := range prev {
+		if !containsKey(attrs, a.Key) {
+			merged = append(merged, a)
+		}
+	}
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+// Request id из контекста ("" - не задан)
+func RequestID(ctx context.Context) string {
+	for _, a := range attrsFromContext(ctx) {
+		if a.Key == KeyRequestID {
+			return a.Value.String()
+		}
+	}
+	return ""
+}
+
+// Контекст фоновой задачи, которая продолжается после ответа на запрос: без отмены и значений
+// контекста запроса (*gin.Context переиспользуется после ответа), но с полями лога запроса
+func Detach(ctx context.Context) context.Context {
+	return context.WithValue(context.Background(), ctxKey{}, attrsFromContext(ctx))
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+func containsKey(attrs []slog.Attr, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// slog.Handler, добавляющий к записи поля из контекста (логирование методами *Context: InfoContext, ErrorContext, ...)
+type ContextHandler struct {
+	slog.Handler
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := attrsFromContext(ctx); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
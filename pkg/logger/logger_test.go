@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"simple_gin_server/configs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// тест JSON логгера: поля запроса из контекста добавляются к записи, повторное поле заменяет прежнее
+func TestNew_ContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := New(configs.LogConfig{Level: "info", Format: FormatJSON}, &buf)
+	require.NoError(t, err)
+
+	ctx := WithAttrs(context.Background(), slog.String(KeyRequestID, "req-1"), slog.String(KeyRoute, "/login"))
+	ctx = WithAttrs(ctx, slog.String(KeyUserID, "7"), slog.String(KeyRoute, "/matches/search"))
+	log.InfoContext(ctx, "search", "results", 3)
+	log.DebugContext(ctx, "ниже уровня логгера")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "search", record["msg"])
+	assert.Equal(t, "req-1", record[KeyRequestID])
+	assert.Equal(t, "7", record[KeyUserID])
+	assert.Equal(t, "/matches/search", record[KeyRoute])
+	assert.Equal(t, float64(3), record["results"])
+	assert.Equal(t, "req-1", RequestID(ctx))
+}
+
+// тест конфига логгера: неизвестные уровень и формат - ошибка
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(configs.LogConfig{Level: "verbose", Format: FormatText}, &bytes.Buffer{})
+	assert.Error(t, err)
+
+	_, err = New(configs.LogConfig{Level: "info", Format: "xml"}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+// тест контекста фоновой задачи: поля лога сохраняются, отмена и значения запроса - нет
+func TestDetach(t *testing.T) {
+	type requestKey struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), requestKey{}, "request"))
+	ctx = WithAttrs(ctx, slog.String(KeyRequestID, "req-1"))
+	cancel()
+
+	detached := Detach(ctx)
+
+	assert.NoError(t, detached.Err())
+	assert.Nil(t, detached.Value(requestKey{}))
+	assert.Equal(t, "req-1", RequestID(detached))
+}
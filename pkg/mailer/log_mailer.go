@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Mailer для локальной разработки и тестов: письма не отправляются, а записываются в лог или writer (файл)
+type LogMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	log  *slog.Logger
+	from string
+}
+
+// Письма выводятся в лог приложения (с request id запроса, в котором отправлено письмо)
+func NewLogMailer(from string, log *slog.Logger) *LogMailer {
+	return &LogMailer{log: log, from: from}
+}
+
+// Письма дописываются в файл path (файл создаётся, если его нет)
+func NewFileMailer(path, from string) (*LogMailer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mail file: %w", err)
+	}
+	return NewWriterMailer(file, from), nil
+}
+
+// Письма записываются в w
+func NewWriterMailer(w io.Writer, from string) *LogMailer {
+	return &LogMailer{w: w, from: from}
+}
+
+// Записывает письмо в формате, близком к RFC 5322
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if m.log != nil {
+		m.log.InfoContext(ctx, "email not sent, logged for local development",
+			"from", m.from, "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "Date: %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), m.from, msg.To, msg.Subject, msg.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"simple_gin_server/configs"
+	"simple_gin_server/pkg/logger"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// тест записи письма: адреса, тема и текст попадают в вывод
+func TestLogMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewWriterMailer(&buf, "noreply@example.com")
+
+	err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "link"})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "From: noreply@example.com\n")
+	assert.Contains(t, buf.String(), "To: user@example.com\nSubject: Hello\n\nlink\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, m.Send(ctx, Message{}), context.Canceled)
+}
+
+// тест выбора реализации по конфигу: с файлом письма дописываются в файл
+func TestNewMailer_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	m, err := NewMailer(configs.MailConfig{From: "noreply@example.com", File: path}, logger.Nop())
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: "first"}))
+	require.NoError(t, m.Send(context.Background(), Message{To: "b@example.com", Subject: "second"}))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: a@example.com")
+	assert.Contains(t, string(content), "To: b@example.com")
+}
+
+// тест выбора реализации по конфигу: без файла письмо пишется во внедрённый логгер
+func TestNewMailer_Log(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := logger.New(configs.LogConfig{Level: "info", Format: logger.FormatJSON}, &buf)
+	require.NoError(t, err)
+	m, err := NewMailer(configs.MailConfig{From: "noreply@example.com"}, log)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "link"}))
+
+	assert.Contains(t, buf.String(), `"to":"user@example.com"`)
+	assert.Contains(t, buf.String(), `"subject":"Hello"`)
+	assert.Contains(t, buf.String(), `"body":"link"`)
+}
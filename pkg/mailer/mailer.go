@@ -0,0 +1,27 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"simple_gin_server/configs"
+)
+
+// Письмо пользователю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Отправка писем пользователям, реализация выбирается конфигом (SMTP, внешний сервис, файл для локальной разработки)
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Создание Mailer по конфигу: если задан файл - письма дописываются в файл, иначе выводятся в лог
+func NewMailer(conf configs.MailConfig, log *slog.Logger) (Mailer, error) {
+	if conf.File != "" {
+		return NewFileMailer(conf.File, conf.From)
+	}
+	return NewLogMailer(conf.From, log), nil
+}
@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"context"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 	"reflect"
-	"simple_gin_server/configs"
 	"simple_gin_server/pkg/jwt_stuff"
+	"simple_gin_server/pkg/logger"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator"
@@ -48,8 +50,15 @@ func ValidateAuthMiddleware(model interface{}) gin.HandlerFunc {
 	}
 }
 
+// Проверки отзыва access токена: черный список по jti, версия токенов пользователя
+// (увеличивается при logout со всех устройств) и активность учётной записи
+type TokenChecker interface {
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	TokenState(ctx context.Context, userId int) (version int, isActive bool, err error)
+}
+
 // ---------------------------------------------------ПОКА В РАЗРАБОТКЕ-----------------------------------------------------
-func AuthMiddleware(config *configs.Config) gin.HandlerFunc {
+func AuthMiddleware(verifier *jwt_stuff.Verifier, tokens TokenChecker, log *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Получаем токен из заголовка
 		authHeader := c.GetHeader("Authorization")
@@ -67,28 +76,109 @@ func AuthMiddleware(config *configs.Config) gin.HandlerFunc {
 			return
 		}
 
-		//Парсим токен
-		token, err := jwt_stuff.ParseTokenWithClaims(c, tokenString, config.Auth.SecretAcc)
+		// Проверяем токен (подпись, срок действия, издатель, аудитория, тип, обязательные claims)
+		claims, err := verifier.Verify(c.Request.Context(), tokenString)
 		if err != nil {
-			log.Println("Invalid token")
+			log.InfoContext(c.Request.Context(), "invalid access token", "error", err)
+			AbortWithTokenError(c, err)
 			return
 		}
 
-		// Проверяем claims
-		if claims, ok := token.Claims.(*jwt_stuff.CustomClaims); ok && token.Valid {
-			// Добавляем данные пользователя в контекст
-			c.Set("user_email", claims.Email)
-			c.Set("user_role", claims.Role) // Важно для RoleMiddleware
-			c.Set("is_active", claims.IsActive)
-			c.Next()
-		} else {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		if !checkTokenRevocation(c, tokens, claims, log) {
+			return
 		}
+
+		// Добавляем данные пользователя в контекст
+		c.Set("user_email", claims.Email)
+		c.Set("user_id", claims.UserId)
+		c.Set("user_role", claims.Role) // Важно для RoleMiddleware
+		c.Set("is_active", claims.IsActive)
+		c.Set("token_id", claims.ID) // jti и время истечения нужны для отзыва токена при logout
+		c.Set("token_expires_at", claims.ExpiresAt.Time)
+		c.Set("session_id", claims.FamilyId)
+
+		// id пользователя во всех записях лога этого запроса
+		c.Request = c.Request.WithContext(logger.WithAttrs(c.Request.Context(), slog.String(logger.KeyUserID, claims.UserId)))
+		c.Next()
+	}
+}
+
+// Email авторизованного пользователя, сохранённый в контексте AuthMiddleware.
+// Если его нет (маршрут без AuthMiddleware), сразу пишет ответ 500
+func UserEmail(c *gin.Context) (string, bool) {
+	// пробуем извлеч Email из контектста
+	email, exists := c.Get("user_email")
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User email not found"})
+		return "", false
+	}
+
+	// Делаем приведение типа к string
+	emailStr, ok := email.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Wrong Email type"})
+		return "", false
+	}
+
+	return emailStr, true
+}
+
+// Преобразует ошибку проверки токена (jwt_stuff.Verifier) в HTTP ответ и прерывает запрос
+func AbortWithTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		c.AbortWithStatusJSON(http.StatusRequestTimeout, gin.H{"error": "request cancelled"})
+	case errors.Is(err, jwt_stuff.ErrTokenExpired):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
+	case errors.Is(err, jwt_stuff.ErrWrongTokenType):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Wrong token type"})
+	case errors.Is(err, jwt_stuff.ErrMissingClaims):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims", "details": err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token", "details": err.Error()})
 	}
 }
 
 // ---------------------------------------------------ПОКА В РАЗРАБОТКЕ-----------------------------------------------------
 
+// проверяет, что access токен не отозван: не в черном списке, выдан после последнего logout со всех устройств,
+// учётная запись активна. При отказе прерывает запрос
+func checkTokenRevocation(c *gin.Context, tokens TokenChecker, claims *jwt_stuff.CustomClaims, log *slog.Logger) bool {
+	revoked, err := tokens.IsAccessTokenRevoked(c, claims.ID)
+	if err != nil {
+		log.ErrorContext(c.Request.Context(), "failed to check access token revocation", "jti", claims.ID, "error", err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Failed to verify token"})
+		return false
+	}
+	if revoked {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		return false
+	}
+
+	userId, err := strconv.Atoi(claims.UserId)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		return false
+	}
+
+	version, isActive, err := tokens.TokenState(c, userId)
+	if err != nil {
+		log.ErrorContext(c.Request.Context(), "failed to get token state", logger.KeyUserID, userId, "error", err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Failed to verify token"})
+		return false
+	}
+	if claims.Version != version {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		return false
+	}
+	if !isActive {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
+		return false
+	}
+
+	return true
+}
+
 func CheckBearerFormat(authHeader string) (string, error) {
 	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
 		return authHeader[7:], nil
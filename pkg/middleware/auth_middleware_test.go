@@ -1,10 +1,17 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"simple_gin_server/pkg/jwt_stuff"
+	"simple_gin_server/pkg/logger"
+	"simple_gin_server/pkg/rbac"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -76,3 +83,177 @@ func TestValidateAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+// тест проверяет, что AuthMiddleware кладёт id, роль и активность пользователя из access токена в контекст
+func TestAuthMiddleware_SetsUserClaims(t *testing.T) {
+	tokens := jwt_stuff.NewJWT("acc-secret", "ref-secret", time.Minute, time.Hour)
+	accessToken, _, err := tokens.GenerateTokens("admin@example.com", "7", "admin", true, 2)
+	assert.NoError(t, err)
+
+	registry := rbac.NewRegistry()
+	registry.AddRole(rbac.Role{Name: "admin"})
+
+	router := gin.New()
+	router.GET("/test", AuthMiddleware(tokens.AccessVerifier(), stubTokens{versions: map[int]int{7: 2}}, logger.Nop()), RoleCheckMiddleware(registry, "admin"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":    c.GetString("user_id"),
+			"user_email": c.GetString("user_email"),
+			"user_role":  c.GetString("user_role"),
+			"is_active":  c.GetBool("is_active"),
+		})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":"7","user_email":"admin@example.com","user_role":"admin","is_active":true}`, w.Body.String())
+}
+
+// состояние токенов пользователей для тестов AuthMiddleware
+type stubTokens struct {
+	revoked  map[string]bool
+	versions map[int]int
+	inactive map[int]bool
+}
+
+func (s stubTokens) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.revoked[jti], nil
+}
+
+func (s stubTokens) TokenState(ctx context.Context, userId int) (int, bool, error) {
+	version, ok := s.versions[userId]
+	if !ok {
+		return 0, false, errors.New("user not found")
+	}
+	return version, !s.inactive[userId], nil
+}
+
+// тест проверяет, что отозванные access токены не принимаются: из черного списка, старой версии
+// (выданные до logout со всех устройств) и токены деактивированных пользователей
+func TestAuthMiddleware_TokenRevocation(t *testing.T) {
+	tokens := jwt_stuff.NewJWT("acc-secret", "ref-secret", time.Minute, time.Hour)
+	accessToken, _, err := tokens.GenerateTokens("user@example.com", "7", "user", true, 1)
+	assert.NoError(t, err)
+	claims, err := jwt_stuff.ParseTokenWithoutVerification(accessToken)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		tokens     stubTokens
+		wantStatus int
+	}{
+		{name: "valid token", tokens: stubTokens{versions: map[int]int{7: 1}}, wantStatus: http.StatusOK},
+		{name: "logged out token", tokens: stubTokens{revoked: map[string]bool{claims.ID: true}, versions: map[int]int{7: 1}}, wantStatus: http.StatusUnauthorized},
+		{name: "logged out everywhere", tokens: stubTokens{versions: map[int]int{7: 2}}, wantStatus: http.StatusUnauthorized},
+		{name: "deactivated user", tokens: stubTokens{versions: map[int]int{7: 1}, inactive: map[int]bool{7: true}}, wantStatus: http.StatusForbidden},
+		{name: "deleted user", tokens: stubTokens{}, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/test", AuthMiddleware(tokens.AccessVerifier(), tt.tokens, logger.Nop()), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"token_id": c.GetString("token_id")})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.JSONEq(t, `{"token_id":"`+claims.ID+`"}`, w.Body.String())
+			}
+		})
+	}
+}
+
+// тест проверяет ответы AuthMiddleware на ошибки проверки токена
+func TestAuthMiddleware_TokenErrors(t *testing.T) {
+	tokens := jwt_stuff.NewJWT("acc-secret", "ref-secret", time.Minute, time.Hour)
+	// refresh токен с подписью ключом access токенов: отличается только типом
+	sameKey := jwt_stuff.NewJWT("acc-secret", "acc-secret", time.Minute, time.Hour)
+	_, refreshToken, err := sameKey.GenerateTokens("user@example.com", "7", "user", true, 0)
+	assert.NoError(t, err)
+	expired := jwt_stuff.NewJWT("acc-secret", "ref-secret", -time.Minute, time.Hour)
+	expiredToken, _, err := expired.GenerateTokens("user@example.com", "7", "user", true, 0)
+	assert.NoError(t, err)
+	foreign := jwt_stuff.NewJWT("other-secret", "ref-secret", time.Minute, time.Hour)
+	foreignToken, _, err := foreign.GenerateTokens("user@example.com", "7", "user", true, 0)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		token     string
+		wantError string
+	}{
+		{name: "expired token", token: expiredToken, wantError: "Token expired"},
+		{name: "refresh token", token: refreshToken, wantError: "Wrong token type"},
+		{name: "bad signature", token: foreignToken, wantError: "Invalid token"},
+		{name: "malformed token", token: "not-a-token", wantError: "Invalid token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/test", AuthMiddleware(tokens.AccessVerifier(), stubTokens{versions: map[int]int{7: 0}}, logger.Nop()), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			var body map[string]any
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantError, body["error"])
+		})
+	}
+}
+
+// тест проверки прав доступа с учётом иерархии ролей
+func TestRequirePermission(t *testing.T) {
+	registry := rbac.NewRegistry()
+	registry.AddRole(rbac.Role{Name: "user", Permissions: []string{rbac.PermMatchesWrite}})
+	registry.AddRole(rbac.Role{Name: "moderator", Parent: "user", Permissions: []string{rbac.PermUsersRead}})
+	registry.AddRole(rbac.Role{Name: "admin", Parent: "moderator", Permissions: []string{rbac.PermUsersDelete}})
+
+	tests := []struct {
+		name       string
+		role       interface{}
+		permission string
+		wantStatus int
+	}{
+		{name: "own permission", role: "user", permission: rbac.PermMatchesWrite, wantStatus: http.StatusOK},
+		{name: "inherited permission", role: "admin", permission: rbac.PermMatchesWrite, wantStatus: http.StatusOK},
+		{name: "moderator reads users", role: "moderator", permission: rbac.PermUsersRead, wantStatus: http.StatusOK},
+		{name: "moderator can not delete users", role: "moderator", permission: rbac.PermUsersDelete, wantStatus: http.StatusForbidden},
+		{name: "unknown role", role: "guest", permission: rbac.PermMatchesWrite, wantStatus: http.StatusForbidden},
+		{name: "no role in context", permission: rbac.PermMatchesWrite, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/test", func(c *gin.Context) {
+				if tt.role != nil {
+					c.Set("user_role", tt.role)
+				}
+			}, RequirePermission(registry, tt.permission), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
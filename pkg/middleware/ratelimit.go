@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"simple_gin_server/pkg/ratelimit"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ограничение частоты запросов группы маршрутов name. Ключ - id пользователя из access токена
+// (если middleware стоит после AuthMiddleware), иначе IP клиента. В ответ добавляются заголовки
+// X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset (секунды до полного восстановления лимита),
+// при превышении - 429 с Retry-After. При ошибке хранилища лимитов запрос пропускается
+func RateLimitMiddleware(limiter ratelimit.Limiter, name string, limit ratelimit.Limit, log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := name + ":ip:" + c.ClientIP()
+		if userId := c.GetString("user_id"); userId != "" {
+			key = name + ":user:" + userId
+		}
+
+		res, err := limiter.Allow(c.Request.Context(), key, limit)
+		if err != nil {
+			log.ErrorContext(c.Request.Context(), "rate limit check failed, request allowed", "key", key, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// длительность в целых секундах с округлением вверх
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"simple_gin_server/pkg/logger"
+	"simple_gin_server/pkg/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// тест ограничения частоты: лимит считается отдельно по пользователям, в ответе заголовки X-RateLimit-*
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute, Burst: 2}
+
+	router := gin.New()
+	router.GET("/test", func(c *gin.Context) {
+		if userId := c.GetHeader("X-Test-User"); userId != "" {
+			c.Set("user_id", userId)
+		}
+	}, RateLimitMiddleware(limiter, "test", limit, logger.Nop()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(userId string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Test-User", userId)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("7")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("X-RateLimit-Reset"))
+
+	w = request("7")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = request("7")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// у другого пользователя свой лимит
+	assert.Equal(t, http.StatusOK, request("8").Code)
+	// без пользователя - лимит по IP
+	assert.Equal(t, http.StatusOK, request("").Code)
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("redis is down")
+}
+
+// тест недоступности хранилища лимитов: запросы пропускаются
+func TestRateLimitMiddleware_LimiterError(t *testing.T) {
+	router := gin.New()
+	router.GET("/test", RateLimitMiddleware(failingLimiter{}, "test", ratelimit.Limit{Requests: 1, Period: time.Minute, Burst: 1}, logger.Nop()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+}
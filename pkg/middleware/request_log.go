@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"simple_gin_server/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	maxRequestIDLen = 128
+)
+
+// Request id запроса: берётся из заголовка X-Request-ID (от балансировщика или другого сервиса) или генерируется,
+// возвращается в ответе и добавляется вместе с маршрутом ко всем записям лога, сделанным с контекстом запроса
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestId) {
+			requestId = uuid.NewString()
+		}
+
+		c.Header(RequestIDHeader, requestId)
+		c.Set(logger.KeyRequestID, requestId)
+		c.Request = c.Request.WithContext(logger.WithAttrs(c.Request.Context(),
+			slog.String(logger.KeyRequestID, requestId),
+			slog.String(logger.KeyRoute, c.FullPath()),
+		))
+		c.Next()
+	}
+}
+
+// request id из заголовка принимается, если он не слишком длинный и состоит из печатных ASCII символов
+// (значение попадает в логи и в заголовок ответа)
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// Запись в лог о каждом запросе после его обработки: метод, путь, статус, длительность.
+// Ответы 5xx логируются с уровнем Error, 4xx - Warn
+func RequestLoggerMiddleware(log *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		// контекст запроса после обработки: AuthMiddleware добавляет в него id пользователя
+		log.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
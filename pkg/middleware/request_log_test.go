@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"simple_gin_server/configs"
+	"simple_gin_server/pkg/logger"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// тест request id: заголовок клиента сохраняется, некорректный или отсутствующий заменяется сгенерированным;
+// в записи лога запроса есть request id, маршрут и id пользователя
+func TestRequestIDMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := logger.New(configs.LogConfig{Level: "info", Format: logger.FormatJSON}, &buf)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), RequestLoggerMiddleware(log))
+	router.GET("/profiles/:id", func(c *gin.Context) {
+		// так id пользователя добавляет AuthMiddleware
+		c.Request = c.Request.WithContext(logger.WithAttrs(c.Request.Context(), slog.String(logger.KeyUserID, "7")))
+		c.String(http.StatusOK, logger.RequestID(c.Request.Context()))
+	})
+
+	tests := []struct {
+		name      string
+		header    string
+		keepsOwn  bool
+		generated bool
+	}{
+		{name: "propagated", header: "req-123", keepsOwn: true},
+		{name: "missing", generated: true},
+		{name: "too long", header: strings.Repeat("a", 200), generated: true},
+		{name: "control characters", header: "req 123\x01", generated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/profiles/5", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			router.ServeHTTP(w, req)
+
+			requestId := w.Header().Get(RequestIDHeader)
+			if tt.keepsOwn {
+				assert.Equal(t, tt.header, requestId)
+			}
+			if tt.generated {
+				assert.Len(t, requestId, 36)
+				assert.NotEqual(t, tt.header, requestId)
+			}
+			assert.Equal(t, requestId, w.Body.String())
+
+			var record map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			assert.Equal(t, "request", record["msg"])
+			assert.Equal(t, requestId, record[logger.KeyRequestID])
+			assert.Equal(t, "/profiles/:id", record[logger.KeyRoute])
+			assert.Equal(t, "7", record[logger.KeyUserID])
+			assert.Equal(t, float64(http.StatusOK), record["status"])
+		})
+	}
+}
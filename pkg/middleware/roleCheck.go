@@ -2,23 +2,55 @@ package middleware
 
 import (
 	"net/http"
+	"simple_gin_server/pkg/rbac"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RoleCheckMiddleware(requiredRole string) gin.HandlerFunc {
+// Пропускает пользователей с ролью requiredRole или ролью, наследующей её (admin -> moderator -> user)
+func RoleCheckMiddleware(registry *rbac.Registry, requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Получаем пользователя из контекста (предполагаем, что он был добавлен в auth middleware)
-		userRole, exists := c.Get("user_role")
-		if !exists {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Role information missing"})
+		role, ok := userRole(c)
+		if !ok {
 			return
 		}
 
-		if requiredRole != userRole {
+		if !registry.Inherits(role, requiredRole) {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 			return
 		}
 		c.Next()
 	}
 }
+
+// Пропускает пользователей, роль которых (с учётом наследования) имеет право permission, например "matches:write"
+func RequirePermission(registry *rbac.Registry, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := userRole(c)
+		if !ok {
+			return
+		}
+
+		if !registry.HasPermission(role, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// извлекает роль пользователя из контекста (добавляется в AuthMiddleware), при ошибке прерывает запрос
+func userRole(c *gin.Context) (string, bool) {
+	userRole, exists := c.Get("user_role")
+	if !exists {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Role information missing"})
+		return "", false
+	}
+
+	role, ok := userRole.(string)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Role information missing"})
+		return "", false
+	}
+	return role, true
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// число ключей, после которого при очередном запросе удаляются ключи с восстановленным лимитом
+const memorySweepSize = 10_000
+
+// Limiter в памяти процесса: для тестов и запуска в одном экземпляре
+type MemoryLimiter struct {
+	mu   sync.Mutex
+	tats map[string]time.Time
+	now  func() time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		tats: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if len(m.tats) >= memorySweepSize {
+		m.sweep(now)
+	}
+
+	res, tat := gcra(now, m.tats[key], limit)
+	m.tats[key] = tat
+	return res, nil
+}
+
+// удаляет ключи, TAT которых в прошлом: для них лимит полностью восстановлен
+func (m *MemoryLimiter) sweep(now time.Time) {
+	for key, tat := range m.tats {
+		if !tat.After(now) {
+			delete(m.tats, key)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"simple_gin_server/configs"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Лимит запросов: в среднем Requests запросов за Period, подряд без пауз - не больше Burst
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// Результат проверки запроса
+type Result struct {
+	Allowed    bool
+	Limit      int           // максимальное число запросов подряд (Burst)
+	Remaining  int           // сколько запросов ещё можно сделать подряд
+	RetryAfter time.Duration // через сколько будет разрешён следующий запрос (для отклонённого запроса)
+	ResetAfter time.Duration // через сколько лимит полностью восстановится
+}
+
+// Ограничение частоты запросов по ключу (пользователь, IP) алгоритмом GCRA (generic cell rate algorithm):
+// для ключа хранится только теоретическое время прибытия следующего запроса (TAT)
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Лимит из конфига, если Burst не задан - равен Requests
+func LimitFromConfig(rule configs.RateLimitRule) Limit {
+	limit := Limit{Requests: rule.Requests, Period: rule.Period, Burst: rule.Burst}
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Requests
+	}
+	return limit
+}
+
+// Создание Limiter по конфигу: "redis" - общий для всех экземпляров сервиса, "memory" - в памяти процесса
+func NewFromConfig(conf configs.RateLimitConfig, client redis.Scripter) (Limiter, error) {
+	switch conf.Backend {
+	case BackendRedis:
+		return NewRedisLimiter(client), nil
+	case BackendMemory:
+		return NewMemoryLimiter(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", conf.Backend)
+	}
+}
+
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+// интервал между запросами при равномерной нагрузке
+func (l Limit) emissionInterval() time.Duration {
+	if l.Requests <= 0 {
+		return l.Period
+	}
+	return l.Period / time.Duration(l.Requests)
+}
+
+// на сколько TAT может опережать текущее время (запас на Burst запросов подряд)
+func (l Limit) burstOffset() time.Duration {
+	return l.emissionInterval() * time.Duration(l.Burst)
+}
+
+// шаг GCRA: tat - сохранённое время прибытия (нулевое для нового ключа).
+// Возвращает результат и новое значение tat (для отклонённого запроса tat не меняется)
+func gcra(now, tat time.Time, limit Limit) (Result, time.Time) {
+	if tat.Before(now) {
+		tat = now
+	}
+
+	interval := limit.emissionInterval()
+	newTat := tat.Add(interval)
+	allowAt := newTat.Add(-limit.burstOffset())
+
+	if now.Before(allowAt) {
+		return Result{
+			Allowed:    false,
+			Limit:      limit.Burst,
+			Remaining:  0,
+			RetryAfter: allowAt.Sub(now),
+			ResetAfter: tat.Sub(now),
+		}, tat
+	}
+
+	return result(limit, newTat.Sub(now)), newTat
+}
+
+// результат разрешённого запроса по тому, насколько TAT опережает текущее время
+func result(limit Limit, ahead time.Duration) Result {
+	remaining := 0
+	if interval := limit.emissionInterval(); interval > 0 {
+		remaining = int((limit.burstOffset() - ahead) / interval)
+	}
+	return Result{
+		Allowed:    true,
+		Limit:      limit.Burst,
+		Remaining:  max(remaining, 0),
+		ResetAfter: ahead,
+	}
+}
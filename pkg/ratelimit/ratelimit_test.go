@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"simple_gin_server/configs"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// тест GCRA в памяти: Burst запросов подряд, затем по одному запросу за интервал
+func TestMemoryLimiter_Allow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 3}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		res, err := limiter.Allow(ctx, "user:7", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, err := limiter.Allow(ctx, "user:7", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.ResetAfter)
+
+	// другой ключ не затронут
+	res, err = limiter.Allow(ctx, "user:8", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// через интервал разрешён ещё один запрос
+	now = now.Add(time.Second)
+	res, err = limiter.Allow(ctx, "user:7", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	// после паузы лимит полностью восстановлен
+	now = now.Add(time.Minute)
+	res, err = limiter.Allow(ctx, "user:7", limit)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+// Scripter, возвращающий заданный результат скрипта
+type stubScripter struct {
+	redis.Scripter
+	reply []interface{}
+	keys  []string
+}
+
+func (s *stubScripter) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) *redis.Cmd {
+	s.keys = keys
+	cmd := redis.NewCmd(ctx)
+	cmd.SetVal(s.reply)
+	return cmd
+}
+
+// тест разбора ответа скрипта GCRA в Redis
+func TestRedisLimiter_Allow(t *testing.T) {
+	limit := Limit{Requests: 60, Period: time.Minute, Burst: 3}
+
+	allowed := &stubScripter{reply: []interface{}{int64(1), int64(2_000_000), int64(0)}}
+	res, err := NewRedisLimiter(allowed).Allow(context.Background(), "search:user:7", limit)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ratelimit:search:user:7"}, allowed.keys)
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 1, ResetAfter: 2 * time.Second}, res)
+
+	denied := &stubScripter{reply: []interface{}{int64(0), int64(500_000), int64(3_000_000)}}
+	res, err = NewRedisLimiter(denied).Allow(context.Background(), "search:user:7", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: false, Limit: 3, RetryAfter: 500 * time.Millisecond, ResetAfter: 3 * time.Second}, res)
+}
+
+// тест выбора хранилища лимитов по конфигу
+func TestNewFromConfig(t *testing.T) {
+	limiter, err := NewFromConfig(configs.RateLimitConfig{Backend: BackendMemory}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryLimiter{}, limiter)
+
+	_, err = NewFromConfig(configs.RateLimitConfig{Backend: "memcached"}, nil)
+	assert.Error(t, err)
+
+	assert.Equal(t, Limit{Requests: 10, Period: time.Minute, Burst: 10}, LimitFromConfig(configs.RateLimitRule{Requests: 10, Period: time.Minute}))
+}
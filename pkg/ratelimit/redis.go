@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// GCRA в Redis одним скриптом (атомарно для всех экземпляров сервиса). Время берётся из Redis (TIME),
+// чтобы расхождение часов экземпляров не влияло на лимит. Все значения - в микросекундах.
+// Возвращает {1, на сколько TAT опережает текущее время} или {0, через сколько повторить, на сколько TAT опережает}
+var gcraScript = redis.NewScript(`
+local key = KEYS[1]
+local interval = tonumber(ARGV[1])
+local burst_offset = tonumber(ARGV[2])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call('GET', key))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - burst_offset
+if now < allow_at then
+	return {0, allow_at - now, tat - now}
+end
+
+redis.call('SET', key, new_tat, 'PX', math.ceil((new_tat - now) / 1000))
+return {1, new_tat - now, 0}
+`)
+
+// Limiter в Redis: общий лимит для нескольких экземпляров сервиса
+type RedisLimiter struct {
+	client redis.Scripter
+	prefix string
+}
+
+func NewRedisLimiter(client redis.Scripter) *RedisLimiter {
+	return &RedisLimiter{client: client, prefix: "ratelimit:"}
+}
+
+func (r *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+
+	values, err := gcraScript.Run(ctx, r.client, []string{r.prefix + key},
+		limit.emissionInterval().Microseconds(),
+		limit.burstOffset().Microseconds(),
+	).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script failed: %w", err)
+	}
+	if len(values) != 3 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	if values[0] == 1 {
+		return result(limit, time.Duration(values[1])*time.Microsecond), nil
+	}
+	return Result{
+		Allowed:    false,
+		Limit:      limit.Burst,
+		RetryAfter: time.Duration(values[1]) * time.Microsecond,
+		ResetAfter: time.Duration(values[2]) * time.Microsecond,
+	}, nil
+}
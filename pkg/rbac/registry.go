@@ -0,0 +1,134 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"simple_gin_server/pkg/db"
+	"sync"
+)
+
+// Права, проверяемые в маршрутах (назначаются ролям в таблице role_permissions)
+const (
+	PermProfilesRead    = "profiles:read"
+	PermProfilesWrite   = "profiles:write"
+	PermMatchesRead     = "matches:read"
+	PermMatchesWrite    = "matches:write"
+	PermUsersRead       = "users:read"
+	PermUsersWrite      = "users:write"
+	PermUsersDelete     = "users:delete"
+	PermMatchesModerate = "matches:moderate"
+)
+
+// Роль: собственные права и родительская роль, права которой наследуются
+type Role struct {
+	Name        string
+	Parent      string // "" - роль без родителя
+	Permissions []string
+}
+
+// Реестр ролей и прав с наследованием (admin -> moderator -> user)
+type Registry struct {
+	mu    sync.RWMutex
+	roles map[string]Role
+}
+
+// Конструктор пустого реестра
+func NewRegistry() *Registry {
+	return &Registry{
+		roles: make(map[string]Role),
+	}
+}
+
+// Добавляет или заменяет роль в реестре
+func (r *Registry) AddRole(role Role) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.roles[role.Name] = role
+}
+
+// Проверяет, есть ли у роли право (собственное или унаследованное от родителей)
+func (r *Registry) HasPermission(roleName, permission string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := false
+	r.walk(roleName, func(role Role) bool {
+		for _, p := range role.Permissions {
+			if p == permission {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// Проверяет, совпадает ли роль с ancestor или наследуется от неё
+// (admin наследует moderator, поэтому Inherits("admin", "moderator") == true)
+func (r *Registry) Inherits(roleName, ancestor string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := false
+	r.walk(roleName, func(role Role) bool {
+		if role.Name == ancestor {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// обходит роль и её родителей, пока fn возвращает true; циклы в иерархии обрываются
+func (r *Registry) walk(roleName string, fn func(role Role) bool) {
+	visited := make(map[string]bool)
+	for roleName != "" && !visited[roleName] {
+		visited[roleName] = true
+
+		role, ok := r.roles[roleName]
+		if !ok || !fn(role) {
+			return
+		}
+		roleName = role.Parent
+	}
+}
+
+// Загружает роли и их права из таблиц roles и role_permissions
+func LoadRegistry(ctx context.Context, pg db.PgRepoInterface) (*Registry, error) {
+	// Проверяем не отменен ли контекст
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	const query = `
+		SELECT r.name, COALESCE(parent.name, ''), COALESCE(array_agg(rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN roles parent ON parent.id = r.parent_id
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		GROUP BY r.name, parent.name
+	`
+
+	rows, err := pg.GetPool().Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer rows.Close()
+
+	registry := NewRegistry()
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.Name, &role.Parent, &role.Permissions); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		registry.AddRole(role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error after iterating rows: %w", err)
+	}
+
+	return registry, nil
+}
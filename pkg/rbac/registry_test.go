@@ -0,0 +1,64 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// реестр с иерархией ролей, как в миграции add_roles_and_permissions
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	r.AddRole(Role{Name: "user", Permissions: []string{PermProfilesRead, PermMatchesWrite}})
+	r.AddRole(Role{Name: "moderator", Parent: "user", Permissions: []string{PermUsersRead}})
+	r.AddRole(Role{Name: "admin", Parent: "moderator", Permissions: []string{PermUsersDelete}})
+	return r
+}
+
+// тест проверки прав с наследованием
+func TestRegistry_HasPermission(t *testing.T) {
+	r := newTestRegistry()
+
+	tests := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{"user", PermMatchesWrite, true},
+		{"user", PermUsersRead, false},
+		{"moderator", PermMatchesWrite, true},
+		{"moderator", PermUsersRead, true},
+		{"moderator", PermUsersDelete, false},
+		{"admin", PermProfilesRead, true},
+		{"admin", PermUsersDelete, true},
+		{"unknown", PermProfilesRead, false},
+		{"", PermProfilesRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+"/"+tt.permission, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.HasPermission(tt.role, tt.permission))
+		})
+	}
+}
+
+// тест наследования ролей
+func TestRegistry_Inherits(t *testing.T) {
+	r := newTestRegistry()
+
+	assert.True(t, r.Inherits("admin", "user"))
+	assert.True(t, r.Inherits("admin", "admin"))
+	assert.True(t, r.Inherits("moderator", "user"))
+	assert.False(t, r.Inherits("user", "moderator"))
+	assert.False(t, r.Inherits("unknown", "user"))
+}
+
+// тест защиты от циклов в иерархии ролей
+func TestRegistry_Cycle(t *testing.T) {
+	r := NewRegistry()
+	r.AddRole(Role{Name: "a", Parent: "b"})
+	r.AddRole(Role{Name: "b", Parent: "a"})
+
+	assert.False(t, r.HasPermission("a", PermUsersRead))
+	assert.True(t, r.Inherits("a", "b"))
+}
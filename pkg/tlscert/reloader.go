@@ -0,0 +1,110 @@
+package tlscert
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Сертификат сервера из PEM файлов с перечитыванием при их изменении (продление сертификата без перезапуска).
+// Файлы проверяются при TLS рукопожатии не чаще раза в interval, если новая пара не загружается -
+// продолжает использоваться прежний сертификат
+type Reloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	log      *slog.Logger
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time // время изменения более нового из двух файлов загруженной пары
+	checkedAt time.Time
+	now       func() time.Time
+}
+
+// Конструктор, сертификат загружается сразу, чтобы ошибка в путях была видна при запуске
+func NewReloader(certFile, keyFile string, interval time.Duration, log *slog.Logger) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: interval,
+		log:      log,
+		now:      time.Now,
+	}
+
+	modTime, err := r.lastModified()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	r.checkedAt = r.now()
+	return r, nil
+}
+
+// TLS конфиг сервера с сертификатом из Reloader
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// Текущий сертификат для tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.checkedAt) >= r.interval {
+		r.checkedAt = now
+		r.reloadIfChanged()
+	}
+	return r.cert, nil
+}
+
+// перечитывает пару, если один из файлов изменился
+func (r *Reloader) reloadIfChanged() {
+	modTime, err := r.lastModified()
+	if err != nil {
+		r.log.Error("TLS certificate check failed, keeping current certificate", "error", err)
+		return
+	}
+	if !modTime.After(r.modTime) {
+		return
+	}
+
+	if err := r.load(modTime); err != nil {
+		r.log.Error("TLS certificate reload failed, keeping current certificate", "error", err)
+		return
+	}
+	r.log.Info("TLS certificate reloaded", "file", r.certFile)
+}
+
+func (r *Reloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// время изменения более нового из файлов сертификата и ключа
+func (r *Reloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("stat TLS file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,80 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"simple_gin_server/pkg/logger"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// записывает самоподписанный сертификат с заданным CN и ключ, выставляя время изменения файлов
+func writeCert(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return parsed.Subject.CommonName
+}
+
+// тест перечитывания: новый сертификат подхватывается после interval, битый файл не заменяет рабочий
+func TestReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeCert(t, certFile, keyFile, "first", modTime)
+
+	r, err := NewReloader(certFile, keyFile, time.Minute, logger.Nop())
+	require.NoError(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	assert.Equal(t, "first", commonName(t, r))
+
+	writeCert(t, certFile, keyFile, "second", modTime.Add(time.Minute))
+	assert.Equal(t, "first", commonName(t, r), "файлы проверяются не чаще раза в interval")
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, "second", commonName(t, r))
+
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime.Add(2*time.Minute), modTime.Add(2*time.Minute)))
+	now = now.Add(time.Minute)
+	assert.Equal(t, "second", commonName(t, r))
+}
+
+// тест ошибки при запуске: неверный путь виден сразу
+func TestNewReloader_MissingFile(t *testing.T) {
+	_, err := NewReloader(filepath.Join(t.TempDir(), "missing.crt"), filepath.Join(t.TempDir(), "missing.key"), time.Minute, logger.Nop())
+	assert.Error(t, err)
+}